@@ -0,0 +1,221 @@
+package echo
+
+import (
+	"net"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	gstatus "google.golang.org/grpc/status"
+)
+
+//===========================================================================
+// Server Admin RPCs
+//===========================================================================
+
+// Status returns a live snapshot of the metrics of the running server.
+func (s *Server) Status(ctx context.Context, in *pb.StatusRequest) (*pb.StatusReply, error) {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+
+	reply := &pb.StatusReply{
+		Name:      s.name,
+		Version:   Version,
+		Transport: s.transport.String(),
+		LogLevel:  LogLevel(),
+		Faults:    s.Faults().String(),
+	}
+
+	if !started.IsZero() {
+		reply.Uptime = time.Since(started).Nanoseconds()
+	}
+
+	// Read the metrics under a single lock so that the snapshot is consistent
+	m := s.metrics
+	m.RLock()
+	reply.Accesses = m.totalAccesses()
+	reply.Clients = uint64(len(m.accesses))
+	reply.Throughput = m.throughput()
+	reply.InFlight = m.inflight
+	reply.Errors = m.totalErrors()
+	reply.Expired = m.expired
+	m.RUnlock()
+
+	return reply, nil
+}
+
+// Reset flushes the metrics collected since the last reset to the outpath of
+// the server, labeled with the run, and begins collecting fresh metrics.
+func (s *Server) Reset(ctx context.Context, in *pb.ResetRequest) (*pb.ResetReply, error) {
+	metrics := s.metrics.Reset()
+	status("reset metrics for run %q: %s", in.Label, metrics)
+
+	if s.outpath != "" {
+		extra := s.extra()
+		extra["label"] = in.Label
+		if err := metrics.Write(s.outpath, extra); err != nil {
+			return nil, gstatus.Errorf(codes.Internal, "could not write metrics: %s", err)
+		}
+	}
+
+	reply := &pb.ResetReply{
+		Label:      in.Label,
+		Accesses:   metrics.Accesses(),
+		Clients:    metrics.NClients(),
+		Duration:   metrics.Duration().Nanoseconds(),
+		Throughput: metrics.Throughput(),
+	}
+	return reply, nil
+}
+
+// Inject replaces the faults injected into replies, returning the faults that
+// are now injected. An empty message stops injecting faults.
+func (s *Server) Inject(ctx context.Context, in *pb.Faults) (*pb.Faults, error) {
+	faults, err := parseFaults(in)
+	if err != nil {
+		return nil, gstatus.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err = s.SetFaults(faults); err != nil {
+		return nil, gstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	return faults.proto(), nil
+}
+
+// registerAdmin registers the admin rpcs, the standard health service and
+// server reflection so that generic gRPC tools can probe the server.
+func registerAdmin(srv *grpc.Server, s *Server) {
+	pb.RegisterAdminServer(srv, s)
+	healthpb.RegisterHealthServer(srv, s.health)
+	reflection.Register(srv)
+}
+
+// serveAdmin serves the admin rpcs, health checks and reflection with gRPC on
+// the address until the context is canceled, using the server's TLS
+// credentials and interceptors if specified.
+func serveAdmin(ctx context.Context, addr string, s *Server) error {
+	sock, err := net.Listen("tcp", addr)
+	if err != nil {
+		return WrapError("could not listen for admin rpcs on '%s'", err, addr)
+	}
+
+	srv := grpc.NewServer(serverOptions(s)...)
+	registerAdmin(srv, s)
+
+	go func() {
+		if err := srv.Serve(sock); err != nil {
+			warne(err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		srv.Stop()
+	}()
+
+	status("serving admin rpcs on %s", sock.Addr())
+	return nil
+}
+
+//===========================================================================
+// Client Admin RPCs
+//===========================================================================
+
+// Status requests a live snapshot of the metrics of the server. The admin
+// rpcs are only available if the client is connected with gRPC, either to a
+// server using the gRPC transport or to the admin address of the server.
+func (c *Client) Status() (*pb.StatusReply, error) {
+	admin, _, timeout, err := c.adminClients()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err := admin.Status(ctx, &pb.StatusRequest{})
+	if err != nil {
+		return nil, WrapError("could not get server status", err)
+	}
+	return reply, nil
+}
+
+// Reset flushes the metrics of the server to its outpath labeled with the run
+// and begins collecting fresh metrics, e.g. between benchmark runs.
+func (c *Client) Reset(label string) (*pb.ResetReply, error) {
+	admin, _, timeout, err := c.adminClients()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err := admin.Reset(ctx, &pb.ResetRequest{Label: label})
+	if err != nil {
+		return nil, WrapError("could not reset server metrics", err)
+	}
+	return reply, nil
+}
+
+// Inject replaces the faults the server injects into its replies, returning
+// the faults that are now injected; the zero value stops injecting faults.
+func (c *Client) Inject(faults Faults) (Faults, error) {
+	admin, _, timeout, err := c.adminClients()
+	if err != nil {
+		return Faults{}, err
+	}
+
+	if err = faults.Validate(); err != nil {
+		return Faults{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err := admin.Inject(ctx, faults.proto())
+	if err != nil {
+		return Faults{}, WrapError("could not inject faults", err)
+	}
+	return parseFaults(reply)
+}
+
+// Health checks whether the server is serving the service with the standard
+// gRPC health service; if the service is empty the server as a whole is checked.
+func (c *Client) Health(service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	_, health, timeout, err := c.adminClients()
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, WrapError("could not check server health", err)
+	}
+	return reply.Status, nil
+}
+
+// adminClients returns the admin and health clients and the request timeout,
+// or an error if the client is not connected with gRPC.
+func (c *Client) adminClients() (pb.AdminClient, healthpb.HealthClient, time.Duration, error) {
+	c.connMu.RLock()
+	conn, admin, health, timeout := c.conn, c.admin, c.health, c.timeout
+	c.connMu.RUnlock()
+
+	if conn == nil {
+		return nil, nil, 0, ErrNotConnected
+	}
+
+	if admin == nil {
+		return nil, nil, 0, WrapError("the %s transport does not support admin rpcs", nil, c.transport)
+	}
+	return admin, health, timeout, nil
+}
@@ -0,0 +1,164 @@
+package echo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestStatus(t *testing.T) {
+	s, c, stop := connect(t, GRPCTransport{})
+	defer stop()
+
+	for i := 0; i < 10; i++ {
+		if err := c.Send("hello"); err != nil {
+			t.Fatalf("could not send message: %s", err)
+		}
+	}
+
+	reply, err := c.Status()
+	if err != nil {
+		t.Fatalf("could not get status: %s", err)
+	}
+
+	if reply.Accesses != 10 {
+		t.Errorf("expected 10 accesses, got %d", reply.Accesses)
+	}
+
+	if reply.Clients != 1 {
+		t.Errorf("expected 1 client, got %d", reply.Clients)
+	}
+
+	if reply.Version != Version || reply.Transport != "grpc" || reply.Name != s.name {
+		t.Errorf("unexpected server info in status: %s", reply)
+	}
+
+	if reply.Uptime <= 0 {
+		t.Errorf("expected a positive uptime, got %s", time.Duration(reply.Uptime))
+	}
+
+	// Status requests are not counted as accesses
+	if n := s.metrics.Accesses(); n != 10 {
+		t.Errorf("expected 10 accesses after status, got %d", n)
+	}
+}
+
+func TestStatusTransport(t *testing.T) {
+	_, c, stop := connect(t, TCPTransport{})
+	defer stop()
+
+	if _, err := c.Status(); err == nil {
+		t.Error("expected admin rpcs to be unsupported by the tcp transport")
+	}
+}
+
+func TestReset(t *testing.T) {
+	s, c, stop := connect(t, GRPCTransport{})
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "metrics.json")
+	s.SetOutpath(path)
+
+	for run, n := range []int{10, 5} {
+		for i := 0; i < n; i++ {
+			if err := c.Send("hello"); err != nil {
+				t.Fatalf("could not send message: %s", err)
+			}
+		}
+
+		reply, err := c.Reset(fmt.Sprintf("run %d", run))
+		if err != nil {
+			t.Fatalf("could not reset metrics: %s", err)
+		}
+
+		if reply.Accesses != uint64(n) {
+			t.Errorf("expected %d accesses in run %d, got %d", n, run, reply.Accesses)
+		}
+	}
+
+	if n := s.metrics.Accesses(); n != 0 {
+		t.Errorf("expected no accesses after reset, got %d", n)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read metrics: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 runs flushed to the metrics, got %d", len(lines))
+	}
+
+	for run, line := range lines {
+		result := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("could not parse metrics: %s", err)
+		}
+
+		if label := fmt.Sprintf("run %d", run); result["label"] != label {
+			t.Errorf("expected label %q, got %v", label, result["label"])
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s, _ := NewServer("", "test")
+	in := &pb.BasicMessage{Sender: "test"}
+	for i := 0; i < 10; i++ {
+		s.Respond(context.Background(), in)
+	}
+
+	snap := s.metrics.Snapshot()
+	s.Respond(context.Background(), in)
+
+	if n := snap.Accesses(); n != 10 {
+		t.Errorf("expected 10 accesses in the snapshot, got %d", n)
+	}
+
+	if n := s.metrics.Accesses(); n != 11 {
+		t.Errorf("expected 11 accesses in the metrics, got %d", n)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s, c, stop := connect(t, GRPCTransport{})
+	defer stop()
+
+	for _, service := range []string{"", HelloService} {
+		status, err := c.Health(service)
+		if err != nil {
+			t.Fatalf("could not check health: %s", err)
+		}
+
+		if status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("expected %q to be serving, got %s", service, status)
+		}
+	}
+
+	// Health checks report not serving as soon as the server begins to drain
+	s.draining()
+	status, err := c.Health("")
+	if err != nil {
+		t.Fatalf("could not check health: %s", err)
+	}
+
+	if status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected server to be not serving while draining, got %s", status)
+	}
+}
@@ -0,0 +1,270 @@
+package echo
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// TokensEnv is the environment variable with the comma separated tokens that
+// the server allows, in addition to any tokens loaded from a file.
+const TokensEnv = "ECHO_TOKENS"
+
+// MaxClockSkew is how far the timestamp of a signed request may differ from
+// the clock of the server before the request is rejected. The server remembers
+// the nonce of each signed request for this long so that a captured signature
+// cannot be replayed, which costs memory in proportion to the request rate.
+const MaxClockSkew = 30 * time.Second
+
+// Metadata key of the credentials of a request and the prefix of the health
+// checks, which are not authenticated so that probes do not require tokens.
+const (
+	authorizationKey = "authorization"
+	healthPrefix     = "/grpc.health.v1.Health/"
+)
+
+// AuthScheme is how the client presents its token to the server.
+type AuthScheme uint8
+
+// Clients either send the token as a bearer token or sign each request with
+// the token so that the token itself is never sent to the server.
+const (
+	BearerAuth AuthScheme = iota
+	HMACAuth
+)
+
+// String returns the name of the scheme used in the authorization metadata.
+func (a AuthScheme) String() string {
+	switch a {
+	case BearerAuth:
+		return "Bearer"
+	case HMACAuth:
+		return "HMAC"
+	}
+	return fmt.Sprintf("AuthScheme(%d)", a)
+}
+
+// LoadTokens reads the tokens that the server allows from the file, one token
+// per line; blank lines and lines beginning with # are ignored.
+func LoadTokens(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, WrapError("could not open tokens", err)
+	}
+	defer f.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, WrapError("could not read tokens", err)
+	}
+	return tokens, nil
+}
+
+// TokensFromEnv returns the comma separated tokens in the TokensEnv variable.
+func TokensFromEnv() []string {
+	var tokens []string
+	for _, token := range strings.Split(os.Getenv(TokensEnv), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+//===========================================================================
+// Server Authentication
+//===========================================================================
+
+// authenticate the credentials in the metadata of a request to the method,
+// returning an unauthenticated status error if they are missing or invalid.
+func (s *Server) authenticate(ctx context.Context, method string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return gstatus.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) != 2 {
+		return gstatus.Error(codes.Unauthenticated, "malformed credentials")
+	}
+
+	switch scheme, cred := parts[0], strings.TrimSpace(parts[1]); {
+	case strings.EqualFold(scheme, BearerAuth.String()):
+		for _, token := range s.tokens {
+			if subtle.ConstantTimeCompare([]byte(cred), []byte(token)) == 1 {
+				return nil
+			}
+		}
+	case strings.EqualFold(scheme, HMACAuth.String()):
+		return s.verify(method, cred)
+	default:
+		return gstatus.Errorf(codes.Unauthenticated, "unsupported auth scheme %q", scheme)
+	}
+
+	return gstatus.Error(codes.Unauthenticated, "invalid token")
+}
+
+// verify the signature of a request signed with one of the allowed tokens,
+// rejecting signatures whose nonce has already been used.
+func (s *Server) verify(method, cred string) error {
+	parts := strings.SplitN(cred, ":", 3)
+	if len(parts) != 3 || parts[1] == "" {
+		return gstatus.Error(codes.Unauthenticated, "malformed signature")
+	}
+
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return gstatus.Error(codes.Unauthenticated, "malformed signature timestamp")
+	}
+
+	if skew := time.Since(time.Unix(0, ts)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return gstatus.Error(codes.Unauthenticated, "signature has expired")
+	}
+
+	signature, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return gstatus.Error(codes.Unauthenticated, "malformed signature")
+	}
+
+	for _, token := range s.tokens {
+		if hmac.Equal(signature, sign(token, method, parts[0], parts[1])) {
+			// Only valid signatures are remembered so the cache cannot be flooded
+			if !s.nonces.use(parts[1], time.Unix(0, ts).Add(MaxClockSkew)) {
+				return gstatus.Error(codes.Unauthenticated, "signature has already been used")
+			}
+			return nil
+		}
+	}
+	return gstatus.Error(codes.Unauthenticated, "invalid signature")
+}
+
+// nonceCache remembers the nonces of signed requests until their signatures
+// expire so that a signed request is only accepted once.
+type nonceCache struct {
+	sync.Mutex
+	seen   map[string]time.Time // nonces mapped to when their signature expires
+	pruned time.Time            // when expired nonces were last removed
+}
+
+// use records the nonce, returning false if it has already been used.
+func (n *nonceCache) use(nonce string, expires time.Time) bool {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.seen == nil {
+		n.seen = make(map[string]time.Time)
+	}
+
+	now := time.Now()
+	if now.Sub(n.pruned) > MaxClockSkew {
+		for key, expiry := range n.seen {
+			if now.After(expiry) {
+				delete(n.seen, key)
+			}
+		}
+		n.pruned = now
+	}
+
+	if _, ok := n.seen[nonce]; ok {
+		return false
+	}
+	n.seen[nonce] = expires
+	return true
+}
+
+// authUnary rejects unary requests that are not authenticated.
+func (s *Server) authUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.admit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authStream rejects streaming requests that are not authenticated.
+func (s *Server) authStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.admit(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// Helper to authenticate requests other than health checks, counting and
+// logging the requests that are rejected.
+func (s *Server) admit(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, healthPrefix) {
+		return nil
+	}
+
+	if err := s.authenticate(ctx, method); err != nil {
+		s.metrics.Reject()
+		debug("rejected %s from %s: %s", method, peerAddr(ctx), gstatus.Convert(err).Message())
+		return err
+	}
+	return nil
+}
+
+//===========================================================================
+// Client Credentials
+//===========================================================================
+
+// authUnary attaches the client's credentials to unary requests.
+func (c *Client) authUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(c.authorize(ctx, method), method, req, reply, cc, opts...)
+}
+
+// authStream attaches the client's credentials to streaming requests.
+func (c *Client) authStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(c.authorize(ctx, method), desc, cc, method, opts...)
+}
+
+// Helper to add the authorization metadata for the method to the context.
+func (c *Client) authorize(ctx context.Context, method string) context.Context {
+	cred := c.token
+	if c.scheme == HMACAuth {
+		ts := strconv.FormatInt(time.Now().UnixNano(), 10)
+		nonce := newNonce()
+		cred = ts + ":" + nonce + ":" + hex.EncodeToString(sign(c.token, method, ts, nonce))
+	}
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, c.scheme.String()+" "+cred)
+}
+
+// Helper to sign the method, timestamp and nonce of a request with the token.
+func sign(token, method, ts, nonce string) []byte {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(method + "\n" + ts + "\n" + nonce))
+	return mac.Sum(nil)
+}
+
+// Helper to create a random nonce that makes each signed request unique.
+func newNonce() string {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Errorf("could not generate nonce: %s", err))
+	}
+	return hex.EncodeToString(nonce)
+}
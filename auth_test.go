@@ -0,0 +1,175 @@
+package echo
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// dial a client presenting the token to the server at the address.
+func dial(t *testing.T, addr, token string, scheme AuthScheme) *Client {
+	c, _ := NewClient(addr, "test", WithToken(token, scheme))
+	if err := c.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	return c
+}
+
+func TestAuthenticate(t *testing.T) {
+	s, _ := NewServer("", "test", WithTokens("secret", "other"))
+	addr, stop := runServer(t, s)
+	defer stop()
+
+	for _, scheme := range []AuthScheme{BearerAuth, HMACAuth} {
+		c := dial(t, addr, "other", scheme)
+		if err := c.Send("hello"); err != nil {
+			t.Errorf("could not send with %s token: %s", scheme, err)
+		}
+
+		if err := c.Stream("hello", 2); err != nil {
+			t.Errorf("could not stream with %s token: %s", scheme, err)
+		}
+
+		if _, err := c.Status(); err != nil {
+			t.Errorf("could not get status with %s token: %s", scheme, err)
+		}
+		c.Close()
+
+		c = dial(t, addr, "wrong", scheme)
+		if err := c.Send("hello"); statusCode(err) != codes.Unauthenticated {
+			t.Errorf("expected %s request with the wrong token to be rejected, got %v", scheme, err)
+		}
+		c.Close()
+	}
+
+	// Requests without credentials are rejected except for health checks
+	c, _ := NewClient(addr, "test")
+	if err := c.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer c.Close()
+
+	if err := c.Send("hello"); statusCode(err) != codes.Unauthenticated {
+		t.Errorf("expected request without a token to be rejected, got %v", err)
+	}
+
+	if _, err := c.Status(); statusCode(err) != codes.Unauthenticated {
+		t.Errorf("expected admin request without a token to be rejected, got %v", err)
+	}
+
+	if status, err := c.Health(""); err != nil || status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected health check without a token to succeed, got %s (%v)", status, err)
+	}
+
+	if n := s.metrics.Rejected(); n != 4 {
+		t.Errorf("expected 4 rejected requests, got %d", n)
+	}
+
+	// Each stream is a single access
+	if n := s.metrics.Accesses(); n != 4 {
+		t.Errorf("expected 4 authenticated accesses, got %d", n)
+	}
+}
+
+func TestExpiredSignature(t *testing.T) {
+	s, _ := NewServer("", "test", WithTokens("secret"))
+	method := "/msg.Hello/Respond"
+
+	for offset, valid := range map[time.Duration]bool{0: true, -time.Hour: false, time.Hour: false} {
+		ts := strconv.FormatInt(time.Now().Add(offset).UnixNano(), 10)
+		nonce := newNonce()
+		cred := ts + ":" + nonce + ":" + hex.EncodeToString(sign("secret", method, ts, nonce))
+		if err := s.verify(method, cred); (err == nil) != valid {
+			t.Errorf("expected signature at offset %s to be valid=%t, got %v", offset, valid, err)
+		}
+	}
+
+	// Signatures are only valid for the method they were signed for
+	ts, nonce := strconv.FormatInt(time.Now().UnixNano(), 10), newNonce()
+	cred := ts + ":" + nonce + ":" + hex.EncodeToString(sign("secret", "/msg.Admin/Reset", ts, nonce))
+	if err := s.verify(method, cred); err == nil {
+		t.Error("expected signature of another method to be invalid")
+	}
+
+	// Signatures without a nonce are malformed
+	cred = ts + ":" + hex.EncodeToString(sign("secret", method, ts, ""))
+	if err := s.verify(method, cred); err == nil {
+		t.Error("expected signature without a nonce to be invalid")
+	}
+}
+
+func TestReplayedSignature(t *testing.T) {
+	s, _ := NewServer("", "test", WithTokens("secret"))
+	method := "/msg.Admin/Reset"
+
+	ts, nonce := strconv.FormatInt(time.Now().UnixNano(), 10), newNonce()
+	cred := ts + ":" + nonce + ":" + hex.EncodeToString(sign("secret", method, ts, nonce))
+	if err := s.verify(method, cred); err != nil {
+		t.Fatalf("expected signature to be valid, got %v", err)
+	}
+
+	if err := s.verify(method, cred); statusCode(err) != codes.Unauthenticated {
+		t.Errorf("expected replayed signature to be rejected, got %v", err)
+	}
+
+	// An invalid signature does not use up the nonce
+	nonce = newNonce()
+	forged := ts + ":" + nonce + ":" + hex.EncodeToString(sign("wrong", method, ts, nonce))
+	if err := s.verify(method, forged); err == nil {
+		t.Fatal("expected forged signature to be invalid")
+	}
+
+	cred = ts + ":" + nonce + ":" + hex.EncodeToString(sign("secret", method, ts, nonce))
+	if err := s.verify(method, cred); err != nil {
+		t.Errorf("expected signature with the nonce of a forgery to be valid, got %v", err)
+	}
+}
+
+func TestAuthTransport(t *testing.T) {
+	s, _ := NewServer("", "test", WithTransport(TCPTransport{}), WithTokens("secret"))
+	if err := s.transport.Serve(context.Background(), nil, s); err == nil {
+		t.Error("expected tokens to be unsupported by the tcp server")
+	}
+
+	c, _ := NewClient("localhost:4157", "test", WithTransport(TCPTransport{}), WithToken("secret", BearerAuth))
+	if err := c.Connect(time.Second); err == nil {
+		t.Error("expected tokens to be unsupported by the tcp client")
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tokens")
+	if err := ioutil.WriteFile(path, []byte("# allowed tokens\nsecret\n\n  other  \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := LoadTokens(path)
+	if err != nil {
+		t.Fatalf("could not load tokens: %s", err)
+	}
+
+	if expected := []string{"secret", "other"}; !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected tokens %v, got %v", expected, tokens)
+	}
+
+	os.Setenv(TokensEnv, "a, b,,c")
+	defer os.Unsetenv(TokensEnv)
+	if tokens, expected := TokensFromEnv(), []string{"a", "b", "c"}; !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected tokens %v from the environment, got %v", expected, tokens)
+	}
+}
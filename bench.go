@@ -3,33 +3,554 @@ package echo
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bbengfort/x/stats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	gstatus "google.golang.org/grpc/status"
 )
 
+//===========================================================================
+// Multi-Client Benchmark
+//===========================================================================
+
+// NewBenchmark creates a benchmark of n independent clients that will all
+// connect to the server at the specified address.
+func NewBenchmark(addr, name string, n int) (*Benchmark, error) {
+	b := new(Benchmark)
+	if err := b.Init(addr, name, n); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Benchmark runs multiple clients concurrently against a single server, each
+// with its own connection and identity, and reports both the per-client and
+// the aggregate throughput and latency of the run.
+type Benchmark struct {
+	addr     string    // address of the server being benchmarked
+	name     string    // name prefix used to identify the clients
+	clients  []*Client // the independent clients to run concurrently
+	hists    string    // path to export the raw latency histograms to
+	progress io.Writer // writes interval snapshots during runs, if not nil
+}
+
+// Init the benchmark by creating n clients with unique names.
+func (b *Benchmark) Init(addr, name string, n int) error {
+	if n < 1 {
+		n = 1
+	}
+
+	// if name is empty string, set it to the hostname
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+
+	b.addr = addr
+	b.name = name
+	b.clients = make([]*Client, 0, n)
+
+	for i := 0; i < n; i++ {
+		// Suffix the client name with its index to ensure unique identities.
+		client, err := NewClient(addr, fmt.Sprintf("%s.%d", name, i+1))
+		if err != nil {
+			return err
+		}
+		b.clients = append(b.clients, client)
+	}
+
+	return nil
+}
+
+// SetRetryPolicy specifies how every client resends messages that fail.
+func (b *Benchmark) SetRetryPolicy(policy *RetryPolicy) {
+	for _, client := range b.clients {
+		client.SetRetryPolicy(policy)
+	}
+}
+
+// SetMode specifies the rpc used by every client to send messages.
+func (b *Benchmark) SetMode(mode Mode, batch int) {
+	for _, client := range b.clients {
+		client.SetMode(mode, batch)
+	}
+}
+
+// SetPayload specifies the request and reply payload sizes of every client.
+func (b *Benchmark) SetPayload(size, replySize PayloadSize) {
+	for _, client := range b.clients {
+		client.SetPayload(size, replySize)
+	}
+}
+
+// SetVerify specifies if every client checks that replies echo requests.
+func (b *Benchmark) SetVerify(verify bool) {
+	for _, client := range b.clients {
+		client.SetVerify(verify)
+	}
+}
+
+// SetRate makes the benchmark open-loop, sending messages at the total rate
+// per second divided evenly among the clients. A rate of zero is closed-loop.
+func (b *Benchmark) SetRate(rate float64, arrivals Arrivals, outstanding int) {
+	for _, client := range b.clients {
+		client.SetRate(rate/float64(len(b.clients)), arrivals, outstanding)
+	}
+}
+
+// SetTransport specifies the protocol every client sends messages with.
+func (b *Benchmark) SetTransport(transport Transport) {
+	for _, client := range b.clients {
+		client.SetTransport(transport)
+	}
+}
+
+// SetDialer specifies how every client connects to the server.
+func (b *Benchmark) SetDialer(dialer Dialer) {
+	for _, client := range b.clients {
+		client.SetDialer(dialer)
+	}
+}
+
+// SetCredentials specifies the TLS credentials of every client connection.
+func (b *Benchmark) SetCredentials(creds credentials.TransportCredentials) {
+	for _, client := range b.clients {
+		client.SetCredentials(creds)
+	}
+}
+
+// SetToken specifies the token each client presents to the server.
+func (b *Benchmark) SetToken(token string, scheme AuthScheme) {
+	for _, client := range b.clients {
+		client.SetToken(token, scheme)
+	}
+}
+
+// SetInterval specifies the width of the windows of the throughput and latency
+// time series recorded by every client; if zero no time series is recorded.
+func (b *Benchmark) SetInterval(interval time.Duration) {
+	for _, client := range b.clients {
+		client.SetInterval(interval)
+	}
+}
+
+// SetProgress specifies a writer to stream a summary of each window of the
+// time series to while the benchmark is running; requires an interval.
+func (b *Benchmark) SetProgress(w io.Writer) {
+	b.progress = w
+}
+
+// SetPhases specifies how long every client sends messages before and after
+// the measured duration of each run without including them in the results.
+func (b *Benchmark) SetPhases(warmup, cooldown time.Duration) {
+	for _, client := range b.clients {
+		client.SetPhases(warmup, cooldown)
+	}
+}
+
+// SetHistogramPath specifies a path to append the raw latency histograms of
+// every run to for later plotting; if empty the histograms are not exported.
+func (b *Benchmark) SetHistogramPath(path string) {
+	b.hists = path
+}
+
+// Connect all of the clients to the server.
+func (b *Benchmark) Connect(timeout time.Duration) error {
+	for _, client := range b.clients {
+		if err := client.Connect(timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close all of the client connections to the server.
+func (b *Benchmark) Close() (err error) {
+	for _, client := range b.clients {
+		if cerr := client.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Run all clients concurrently for the specified duration then write the
+// aggregate and per-client results to disk.
+func (b *Benchmark) Run(duration time.Duration, results string) error {
+	if duration <= 0 {
+		return WrapError("cannot run benchmark for a duration of %s", nil, duration)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(b.clients))
+	if warmup, cooldown := b.clients[0].warmup, b.clients[0].cooldown; warmup > 0 || cooldown > 0 {
+		status("starting benchmark of %d clients for %s with %s warm-up and %s cool-down", len(b.clients), duration, warmup, cooldown)
+	} else {
+		status("starting benchmark of %d clients for %s", len(b.clients), duration)
+	}
+
+	start := time.Now()
+	for _, client := range b.clients {
+		client.reset(start)
+	}
+
+	// The reporter must exit before the series are read for the results or
+	// reset by the next run
+	var reporter sync.WaitGroup
+	done := make(chan struct{})
+	if b.progress != nil && b.clients[0].interval > 0 {
+		reporter.Add(1)
+		go func() {
+			defer reporter.Done()
+			reportProgress(b.progress, b.clients[0].interval, done, b.snapshot)
+		}()
+	}
+
+	for i, client := range b.clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			errs[i] = client.run(duration)
+		}(i, client)
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(done)
+	reporter.Wait()
+
+	// Throughput is computed over the measured phase only
+	if b.clients[0].warmup > 0 || b.clients[0].cooldown > 0 {
+		elapsed = duration
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := b.unreachable(); err != nil {
+		return err
+	}
+
+	return b.Results(results, elapsed)
+}
+
+// unreachable returns an error if no message completed because every attempt
+// failed to connect to the server, e.g. a TLS handshake without credentials,
+// so that the benchmark does not report empty results as a success.
+func (b *Benchmark) unreachable() error {
+	var (
+		messages, failures, timeouts, connects uint64
+		err                                    error
+	)
+
+	for _, client := range b.clients {
+		client.mu.Lock()
+		messages += client.messages
+		failures += client.nFailed
+		timeouts += client.nTimeout
+		connects += client.nConnect
+		if client.connErr != nil {
+			err = client.connErr
+		}
+		client.mu.Unlock()
+	}
+
+	if messages > 0 || timeouts > 0 || failures == 0 || connects < failures {
+		return nil
+	}
+	return WrapError("could not connect to the server, all %d messages failed", err, failures)
+}
+
+// snapshot aggregates the last complete window of every client's time series.
+func (b *Benchmark) snapshot(at time.Time) (int, Window) {
+	var (
+		index  = -1
+		window Window
+	)
+
+	for _, client := range b.clients {
+		i, w := client.series.Snapshot(at)
+		if i > index {
+			index = i
+		}
+		window.Add(w)
+	}
+	return index, window
+}
+
+// Sweep runs the benchmark once for each of the request payload sizes, all
+// other settings are unchanged, writing one line of results per size.
+func (b *Benchmark) Sweep(sizes []int, duration time.Duration, results string) error {
+	for _, size := range sizes {
+		for _, client := range b.clients {
+			client.SetPayload(FixedSize(size), client.replySize)
+		}
+
+		status("sweeping request payload size of %d bytes", size)
+		if err := b.Run(duration, results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Results aggregates the client results and appends them to the results file.
+func (b *Benchmark) Results(path string, elapsed time.Duration) error {
+	var (
+		messages uint64
+		retries  uint64
+		failures uint64
+		timeouts uint64
+		late     uint64
+		dropped  uint64
+		sent     uint64
+		recv     uint64
+		latency  time.Duration
+	)
+
+	distribution := new(stats.Statistics)
+	histogram := NewHistogram()
+	clients := make([]map[string]interface{}, 0, len(b.clients))
+
+	var series *Series
+	if interval := b.clients[0].interval; interval > 0 {
+		series = NewSeries(interval, time.Time{})
+	}
+
+	for _, client := range b.clients {
+		retries += atomic.LoadUint64(&client.nRetries)
+		sent += atomic.LoadUint64(&client.nBytes)
+		recv += atomic.LoadUint64(&client.nBytesRecv)
+
+		client.mu.Lock()
+		messages += client.messages
+		failures += client.nFailed
+		timeouts += client.nTimeout
+		late += client.nLate
+		dropped += client.nDropped
+		latency += client.latency
+		distribution.Append(client.stats)
+		histogram.Merge(client.hist)
+
+		if series != nil {
+			series.Merge(client.series)
+		}
+		client.mu.Unlock()
+
+		clients = append(clients, client.Serialize(nil))
+	}
+
+	data := make(map[string]interface{})
+	data["name"] = b.name
+	data["n_clients"] = len(b.clients)
+	data["mode"] = b.clients[0].mode.String()
+	if b.clients[0].mode.batched() {
+		data["batch"] = b.clients[0].batch
+	}
+	data["transport"] = b.clients[0].transport.String()
+	data["tls"] = b.clients[0].creds != nil
+	data["request size"] = sizeSpec(b.clients[0].size)
+	data["reply size"] = sizeSpec(b.clients[0].replySize)
+	data["messages"] = messages
+	data["retries"] = retries
+	data["failures"] = failures
+	data["timeouts"] = timeouts
+	data["duration (nsec)"] = elapsed.Nanoseconds()
+	data["latency (nsec)"] = latency.Nanoseconds()
+	data["throughput (msg/sec)"] = 0.0
+	data["bytes sent"] = sent
+	data["bytes recv"] = recv
+	data["throughput (bytes/sec)"] = 0.0
+	data["latency distribution"] = distribution.Serialize()
+	data["latency percentiles (nsec)"] = histogram.Serialize()
+	data["clients"] = clients
+
+	if series != nil {
+		data["interval (nsec)"] = series.Interval().Nanoseconds()
+		data["series"] = series.Serialize()
+	}
+
+	if client := b.clients[0]; client.warmup > 0 || client.cooldown > 0 {
+		phases := b.phases()
+		data["warmup (nsec)"] = client.warmup.Nanoseconds()
+		data["cooldown (nsec)"] = client.cooldown.Nanoseconds()
+		data["measured from"] = phases[0]
+		data["measured to"] = phases[1]
+	}
+
+	if elapsed > 0 {
+		data["throughput (msg/sec)"] = float64(messages) / elapsed.Seconds()
+		data["throughput (bytes/sec)"] = float64(sent+recv) / elapsed.Seconds()
+	}
+
+	if messages > 0 {
+		data["latency per message (nsec)"] = latency.Nanoseconds() / int64(messages)
+	}
+
+	data["loop"] = "closed"
+	if rate := b.clients[0].rate; rate > 0 {
+		data["loop"] = "open"
+		data["rate (msg/sec)"] = rate * float64(len(b.clients))
+		data["arrivals"] = b.clients[0].arrivals.String()
+		data["late"] = late
+		data["dropped"] = dropped
+	}
+
+	debug("writing results to %s", path)
+	status(
+		"%d messages from %d clients in %0.3f seconds - %0.3f msg/sec, p99 latency %s",
+		messages, len(b.clients), elapsed.Seconds(), data["throughput (msg/sec)"],
+		time.Duration(histogram.Percentile(99)),
+	)
+
+	if err := appendJSON(path, data); err != nil {
+		return err
+	}
+	return b.exportHistograms(data, histogram)
+}
+
+// phases returns the earliest beginning and latest end of the measured phases
+// of the clients, formatted as timestamps for the results.
+func (b *Benchmark) phases() [2]string {
+	var from, to int64
+	for _, client := range b.clients {
+		if f := atomic.LoadInt64(&client.phaseFrom); from == 0 || f < from {
+			from = f
+		}
+		if t := atomic.LoadInt64(&client.phaseTo); t > to {
+			to = t
+		}
+	}
+
+	return [2]string{
+		time.Unix(0, from).Format(time.RFC3339Nano),
+		time.Unix(0, to).Format(time.RFC3339Nano),
+	}
+}
+
+// exportHistograms appends the aggregate and per-client latency histogram
+// buckets of the run to the histogram path, if one has been specified.
+func (b *Benchmark) exportHistograms(results map[string]interface{}, histogram *Histogram) error {
+	if b.hists == "" {
+		return nil
+	}
+
+	clients := make(map[string]interface{})
+	for _, client := range b.clients {
+		clients[client.identity] = client.hist.Buckets()
+	}
+
+	data := make(map[string]interface{})
+	for _, key := range []string{"name", "n_clients", "mode", "loop", "request size", "reply size"} {
+		data[key] = results[key]
+	}
+	data["unit"] = "nsec"
+	data["buckets"] = histogram.Buckets()
+	data["clients"] = clients
+
+	debug("writing latency histograms to %s", b.hists)
+	return appendJSON(b.hists, data)
+}
+
+//===========================================================================
+// Single Client Benchmark
+//===========================================================================
+
 // Benchmark the throughput in terms of messages per second to the zmqnet.
 func (c *Client) Benchmark(duration time.Duration, results string, nClients int) error {
+	status("starting benchmark for %s", duration)
+	c.reset(time.Now())
+	if err := c.run(duration); err != nil {
+		return err
+	}
+
+	// Initialize the results
+	extra := make(map[string]interface{})
+	extra["n_clients"] = nClients
+	return c.Results(results, extra)
+}
+
+// run the benchmark for the specified duration, either open-loop if the
+// client has a rate or closed-loop otherwise. The results must be reset first.
+func (c *Client) run(duration time.Duration) error {
+	if c.mode != Unary {
+		if err := c.streaming(); err != nil {
+			return err
+		}
+	}
+
+	// Messages are only measured between the warm-up and cool-down phases
+	start := time.Now()
+	if c.warmup > 0 || c.cooldown > 0 {
+		from := start.Add(c.warmup)
+		atomic.StoreInt64(&c.phaseFrom, from.UnixNano())
+		atomic.StoreInt64(&c.phaseTo, from.Add(duration).UnixNano())
+	}
+
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.elapsed = time.Since(start)
+		if c.warmup > 0 || c.cooldown > 0 {
+			c.elapsed = duration
+		}
+	}()
+
+	total := c.warmup + duration + c.cooldown
+	if c.rate > 0 {
+		return c.runOpen(total)
+	}
+	return c.runClosed(total)
+}
+
+// reset the results of the client before a run that begins at start.
+func (c *Client) reset(start time.Time) {
+	atomic.StoreUint64(&c.nSent, 0)
+	atomic.StoreUint64(&c.nRecv, 0)
+	atomic.StoreUint64(&c.nBytes, 0)
+	atomic.StoreUint64(&c.nBytesRecv, 0)
+	atomic.StoreUint64(&c.nRetries, 0)
+	atomic.StoreInt64(&c.phaseFrom, 0)
+	atomic.StoreInt64(&c.phaseTo, 0)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Initialize the client
 	c.messages = 0
 	c.latency = 0
-	c.nSent = 0
-	c.nRecv = 0
-	c.nBytes = 0
+	c.elapsed = 0
+	c.nFailed = 0
+	c.nConnect = 0
+	c.connErr = nil
+	c.nTimeout = 0
+	c.nLate = 0
+	c.nDropped = 0
 	c.stats = new(stats.Statistics)
+	c.hist = NewHistogram()
 
-	// Initialize the results
-	extra := make(map[string]interface{})
-	extra["n_clients"] = nClients
-	extra["name"] = c.identity
+	c.series = nil
+	if c.interval > 0 {
+		c.series = NewSeries(c.interval, start)
+	}
+}
 
+// runClosed sends messages to the server one at a time until the duration
+// has elapsed, tracking the latency of every message sent.
+func (c *Client) runClosed(duration time.Duration) error {
 	// Initialize channels
 	timer := time.NewTimer(duration)
 	echan := make(chan error, 1)
 	done := make(chan bool, 1)
-	status("starting benchmark for %s", duration)
 
 	// Send the first access
 	go c.Access(done, echan)
@@ -38,52 +559,277 @@ func (c *Client) Benchmark(duration time.Duration, results string, nClients int)
 	for {
 		select {
 		case <-timer.C:
-			// Benchmarking complete
-			return c.Results(results, extra)
+			// Benchmarking complete, wait for the in-flight access to finish
+			// so that the results are not modified while being reported.
+			select {
+			case <-done:
+			case <-echan:
+			}
+			return nil
 		case err := <-echan:
 			// Something went wrong
+			timer.Stop()
 			return err
 		case <-done:
 			go c.Access(done, echan)
 		}
 	}
+}
+
+// runOpen sends messages to the server on a fixed schedule at the client's
+// rate until the duration has elapsed, without waiting for replies. Latency is
+// measured from the time each message was scheduled to be sent so that it
+// includes queueing delay. Messages are dropped if too many are in-flight and
+// are late if they were sent more than one interval after their schedule.
+func (c *Client) runOpen(duration time.Duration) error {
+	if c.mode == BidiStream {
+		return WrapError("open-loop benchmarks do not support the %s mode", nil, c.mode)
+	}
+
+	// Each rpc is scheduled for the messages it carries; the schedule never
+	// advances if the interval rounds down to zero
+	perRPC := c.perRPC()
+	interval := time.Duration(float64(time.Second) * float64(perRPC) / c.rate)
+	if !(c.rate > 0) || interval <= 0 {
+		return WrapError("cannot schedule messages at a rate of %v per second", nil, c.rate)
+	}
+
+	inflight := make(chan struct{}, c.outstanding)
+	echan := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	next := time.Now()
+	finish := next.Add(duration)
+
+	for {
+		if next = next.Add(c.arrivals.Next(interval)); next.After(finish) {
+			return nil
+		}
+
+		// Wait until the message is scheduled to be sent
+		if wait := time.Until(next); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		select {
+		case err := <-echan:
+			return err
+		default:
+		}
+
+		measured := c.measuring(next)
+		if time.Since(next) > interval && measured {
+			c.mu.Lock()
+			c.nLate += uint64(perRPC)
+			c.mu.Unlock()
+		}
+
+		select {
+		case inflight <- struct{}{}:
+			wg.Add(1)
+			go func(scheduled time.Time) {
+				defer wg.Done()
+				defer func() { <-inflight }()
+
+				if err := c.access(scheduled); err != nil {
+					select {
+					case echan <- err:
+					default:
+					}
+				}
+			}(next)
+		default:
+			if measured {
+				c.mu.Lock()
+				c.nDropped += uint64(perRPC)
+				c.mu.Unlock()
+			}
+		}
+	}
+}
 
+// Helper to get the number of messages carried by each rpc of the mode.
+func (c *Client) perRPC() int {
+	if c.mode.batched() {
+		return c.batch
+	}
+	return 1
 }
 
 // Access sends a request to the server and waits for a response, measuring
-// the latency of the message send to get throughput benchmarks.
+// the latency of the message send to get throughput benchmarks. Messages that
+// fail after all retries are counted as timeouts or failures rather than
+// stopping the benchmark, only client errors are sent on the error channel.
 func (c *Client) Access(done chan<- bool, echan chan<- error) {
-	// Prepare the send
-	message := fmt.Sprintf("msg %d at %s", c.messages+1, time.Now())
-	start := time.Now()
-
-	// Send the request
-	if err := c.Send(message); err != nil {
+	if err := c.access(time.Now()); err != nil {
 		echan <- err
 		return
 	}
 
+	// Signal done
+	done <- true
+}
+
+// access sends a request using the rpc specified by the benchmark mode and
+// records its latency from the start time. Only client errors are returned.
+func (c *Client) access(start time.Time) (err error) {
+	// Prepare the send
+	c.mu.Lock()
+	seq := c.messages + 1
+	c.mu.Unlock()
+
+	message := fmt.Sprintf("msg %d at %s", seq, start)
+	messages := uint64(1)
+
+	// Send the request using the rpc specified by the benchmark mode
+	switch c.mode {
+	case ServerStream:
+		messages = uint64(c.batch)
+		err = c.Stream(message, c.batch)
+	case ClientStream:
+		batch := make([]string, c.batch)
+		for i := range batch {
+			batch[i] = fmt.Sprintf("msg %d at %s", seq+uint64(i), start)
+		}
+		messages = uint64(c.batch)
+		err = c.Collect(batch)
+	case BidiStream:
+		err = c.Echo(message)
+	default:
+		err = c.Send(message)
+	}
+
 	// Compute the throughput
 	latency := time.Since(start)
-	c.messages++
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.series != nil && err == nil {
+		c.series.Record(start.Add(latency), messages, latency)
+	}
+
+	if err != nil {
+		if err == ErrNotConnected {
+			return err
+		}
+
+		// Failures during the warm-up and cool-down phases are not counted
+		debug("message failed: %s", err)
+		if !c.measuring(start) {
+			return nil
+		}
+
+		if statusCode(err) == codes.DeadlineExceeded {
+			c.nTimeout++
+		} else {
+			c.nFailed++
+			if connectionError(err) {
+				c.nConnect++
+				c.connErr = err
+			}
+		}
+		return nil
+	}
+
+	if !c.measuring(start) {
+		return nil
+	}
+
+	c.messages += messages
 	c.latency += latency
 	c.stats.Update(float64(latency))
-
-	// Signal done
-	done <- true
+	c.hist.RecordDuration(latency)
+	return nil
 }
 
-// Results saves the throughput to disk
-func (c *Client) Results(path string, data map[string]interface{}) error {
-	debug("writing results to %s", path)
+// Serialize the results of the client's most recent benchmark run.
+func (c *Client) Serialize(extra map[string]interface{}) map[string]interface{} {
+	sent := atomic.LoadUint64(&c.nBytes)
+	recv := atomic.LoadUint64(&c.nBytesRecv)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := make(map[string]interface{})
+	data["name"] = c.identity
+	data["mode"] = c.mode.String()
+	data["transport"] = c.transport.String()
+	data["loop"] = "closed"
 	data["messages"] = c.messages
+	data["retries"] = atomic.LoadUint64(&c.nRetries)
+	data["failures"] = c.nFailed
+	data["timeouts"] = c.nTimeout
 	data["latency (nsec)"] = c.latency.Nanoseconds()
-	data["throughput (msg/sec)"] = float64(c.messages) / c.latency.Seconds()
+	data["bytes sent"] = sent
+	data["bytes recv"] = recv
+	data["throughput (msg/sec)"] = 0.0
+	data["throughput (bytes/sec)"] = 0.0
 	data["latency distribution"] = c.stats.Serialize()
-	status("%d messages in %0.3f seconds - %0.3f msg/sec", c.messages, c.latency.Seconds(), data["throughput (msg/sec)"])
+	data["latency percentiles (nsec)"] = c.hist.Serialize()
+
+	if c.series != nil {
+		data["interval (nsec)"] = c.interval.Nanoseconds()
+		data["series"] = c.series.Serialize()
+	}
+
+	if c.warmup > 0 || c.cooldown > 0 {
+		data["warmup (nsec)"] = c.warmup.Nanoseconds()
+		data["cooldown (nsec)"] = c.cooldown.Nanoseconds()
+		data["measured from"] = time.Unix(0, atomic.LoadInt64(&c.phaseFrom)).Format(time.RFC3339Nano)
+		data["measured to"] = time.Unix(0, atomic.LoadInt64(&c.phaseTo)).Format(time.RFC3339Nano)
+	}
+
+	// Open-loop messages overlap, so throughput is measured over the run
+	busy := c.latency
+	if c.rate > 0 {
+		busy = c.elapsed
+		data["loop"] = "open"
+		data["rate (msg/sec)"] = c.rate
+		data["arrivals"] = c.arrivals.String()
+		data["late"] = c.nLate
+		data["dropped"] = c.nDropped
+	}
+
+	if busy > 0 {
+		data["throughput (msg/sec)"] = float64(c.messages) / busy.Seconds()
+		data["throughput (bytes/sec)"] = float64(sent+recv) / busy.Seconds()
+	}
+
+	if c.messages > 0 {
+		data["latency per message (nsec)"] = c.latency.Nanoseconds() / int64(c.messages)
+	}
+
+	for key, val := range extra {
+		data[key] = val
+	}
+
+	return data
+}
+
+// Results saves the throughput to disk
+func (c *Client) Results(path string, extra map[string]interface{}) error {
+	debug("writing results to %s", path)
+	data := c.Serialize(extra)
+
+	c.mu.Lock()
+	messages, latency := c.messages, c.latency
+	c.mu.Unlock()
+
+	status("%d messages in %0.3f seconds - %0.3f msg/sec", messages, latency.Seconds(), data["throughput (msg/sec)"])
 	return appendJSON(path, data)
 }
 
+// Helper function to describe a payload size in the results.
+func sizeSpec(size PayloadSize) string {
+	if size == nil {
+		return "0"
+	}
+	return size.String()
+}
+
 // Helper function to append json data as a one line string to the end of a
 // results file without deleting the previous contents in it.
 func appendJSON(path string, val interface{}) error {
@@ -107,3 +853,16 @@ func appendJSON(path string, val interface{}) error {
 	_, err = f.Write(data)
 	return err
 }
+
+// Helper to determine if a message failed because the client could not reach
+// the server rather than because of the reply, unwrapping library errors.
+func connectionError(err error) bool {
+	if e, ok := err.(*Error); ok && e.err != nil {
+		return connectionError(e.err)
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	return gstatus.Code(err) == codes.Unavailable
+}
@@ -1,35 +1,95 @@
 package echo
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	pb "github.com/bbengfort/echo/msg"
 	"github.com/bbengfort/x/stats"
+	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-func NewClient(addr, name string) (*Client, error) {
+func NewClient(addr, name string, opts ...Option) (*Client, error) {
 	c := new(Client)
 	c.Init(addr, name)
+	for _, opt := range opts {
+		if err := opt.applyClient(c); err != nil {
+			return nil, err
+		}
+	}
 	return c, nil
 }
 
 type Client struct {
-	name     string            // host information for the server
-	addr     string            // address to bind the server to
-	nSent    uint64            // number of messages sent
-	nRecv    uint64            // number of messages received
-	nBytes   uint64            // number of bytes sent
+	name      string                           // host information for the server
+	addr      string                           // address to bind the server to
+	identity  string                           // the identity being sent to the server
+	creds     credentials.TransportCredentials // tls credentials, nil if insecure
+	transport Transport                        // the protocol used to send messages
+	dialer    Dialer                           // connects to the server, nil uses the network
+	unary     []grpc.UnaryClientInterceptor    // chain wrapping unary grpc requests
+	streams   []grpc.StreamClientInterceptor   // chain wrapping streaming grpc requests
+	keepalive time.Duration                    // how often idle grpc connections are pinged
+	pingWait  time.Duration                    // how long to wait for a ping before closing
+	maxSize   int                              // largest grpc message received or sent, 0 is default
+	token     string                           // presented with each request, empty for none
+	scheme    AuthScheme                       // how the token is presented to the server
+	connMu    sync.RWMutex                     // protects the connection and timeout
+	conn      Conn                             // the connection to the server
+	stream    pb.HelloClient                   // the grpc client for streaming rpcs
+	admin     pb.AdminClient                   // the grpc client for admin rpcs
+	health    healthpb.HealthClient            // the grpc client for health checks
+	retries   *RetryPolicy                     // how to resend messages that fail
+	timeout   time.Duration                    // the deadline for each request to the server
+	verify    bool                             // check that replies echo the request
+
+	echoMu     sync.Mutex          // serializes messages on the bidirectional stream
+	echo       pb.Hello_EchoClient // the bidirectional stream, opened on demand
+	echoCancel context.CancelFunc  // cancels the bidirectional stream on close
+
+	// counters are accessed atomically
+	nSent      uint64 // number of messages sent
+	nRecv      uint64 // number of messages received
+	nBytes     uint64 // number of bytes sent
+	nBytesRecv uint64 // number of bytes received
+	nRetries   uint64 // number of times a message was resent
+	phaseFrom  int64  // unix nanoseconds the measured phase begins, 0 if unbounded
+	phaseTo    int64  // unix nanoseconds the measured phase ends, 0 if unbounded
+
+	mode        Mode          // the type of rpc used to benchmark the server
+	batch       int           // the number of messages per streaming rpc
+	rate        float64       // open-loop messages per second, 0 is closed-loop
+	arrivals    Arrivals      // the schedule of open-loop messages
+	outstanding int           // maximum open-loop messages in-flight
+	size        PayloadSize   // generates the size of request payloads
+	replySize   PayloadSize   // generates the size of the requested replies
+	interval    time.Duration // the width of the time series windows, 0 for none
+	warmup      time.Duration // time to send messages before measuring
+	cooldown    time.Duration // time to send messages after measuring
+
+	mu       sync.Mutex        // protects the benchmark results
+	nFailed  uint64            // number of messages that could not be sent
+	nConnect uint64            // number of failures connecting to the server
+	connErr  error             // the most recent failure connecting to the server
+	nTimeout uint64            // number of messages that exceeded the deadline
+	nLate    uint64            // number of open-loop messages sent behind schedule
+	nDropped uint64            // number of open-loop messages not sent
 	messages uint64            // the number of messages composed
 	latency  time.Duration     // total time to send messages
+	elapsed  time.Duration     // wall time of the benchmark run
 	stats    *stats.Statistics // distribution of message latency
-	identity string            // the identity being sent to the server
-	conn     *grpc.ClientConn  // the connection to the grpc server
-	stream   pb.HelloClient    // the stream to send messages on
+	hist     *Histogram        // histogram of message latency for percentiles
+	series   *Series           // throughput and latency over the run
 }
 
 func (c *Client) Init(addr, name string) {
@@ -45,28 +105,174 @@ func (c *Client) Init(addr, name string) {
 	// NOTE: the identity must be unique - do not rely on randomness since
 	// parallel instantiation may result in the same seed!
 	c.identity = fmt.Sprintf("%s-%04X", c.name, rand.Intn(0x10000))
+	c.batch = DefaultBatch
+	c.outstanding = DefaultOutstanding
+	c.transport = GRPCTransport{}
+}
+
+// SetRetryPolicy specifies how the client resends messages that fail; if the
+// policy is nil then the client will not retry messages.
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	c.retries = policy
+}
+
+// SetMode specifies the type of rpc used to send messages during benchmarks
+// and the number of messages sent or received per rpc in the streaming modes.
+func (c *Client) SetMode(mode Mode, batch int) {
+	if batch < 1 {
+		batch = 1
+	}
+
+	c.mode = mode
+	c.batch = batch
+}
+
+// SetRate specifies the number of messages per second that the client sends
+// in an open-loop benchmark, scheduled by the arrivals, with at most the
+// outstanding number of rpcs in-flight. In the server and client streaming
+// modes each rpc carries a batch of messages, so rpcs are scheduled at the rate
+// divided by the batch. A rate of zero is closed-loop.
+func (c *Client) SetRate(rate float64, arrivals Arrivals, outstanding int) {
+	if outstanding < 1 {
+		outstanding = DefaultOutstanding
+	}
+
+	c.rate = rate
+	c.arrivals = arrivals
+	c.outstanding = outstanding
+}
+
+// SetPayload specifies the sizes of the payloads sent in requests and the
+// sizes of the payloads requested in replies; nil sizes mean no payloads.
+func (c *Client) SetPayload(size, replySize PayloadSize) {
+	c.size = size
+	c.replySize = replySize
+
+	// Fill the payload pattern now so that it is not measured as latency
+	if fixed, ok := size.(FixedSize); ok {
+		Payload(int(fixed))
+	}
+}
+
+// SetInterval specifies the width of the windows of the throughput and latency
+// time series recorded during benchmarks; if zero no time series is recorded.
+func (c *Client) SetInterval(interval time.Duration) {
+	c.interval = interval
+}
+
+// SetPhases specifies how long benchmarks send messages before and after the
+// measured duration. Messages sent during the warm-up and cool-down phases are
+// excluded from the results but are included in the time series.
+func (c *Client) SetPhases(warmup, cooldown time.Duration) {
+	c.warmup = warmup
+	c.cooldown = cooldown
+}
+
+// SetVerify specifies if the client checks that every reply echoes the
+// message and payload of its request, which requires the server to use the
+// echo reply strategy. Replies that do not match fail with ErrIntegrity.
+func (c *Client) SetVerify(verify bool) {
+	c.verify = verify
+}
+
+// SetTransport specifies the protocol used to send messages to the server,
+// which must match the transport of the server. Only the grpc transport
+// supports the streaming modes.
+func (c *Client) SetTransport(transport Transport) {
+	c.transport = transport
+}
+
+// SetCredentials specifies the TLS credentials used to secure the connection
+// to the server; if nil the client connects insecurely.
+func (c *Client) SetCredentials(creds credentials.TransportCredentials) {
+	c.creds = creds
 }
 
+// SetDialer specifies how the client connects to the server at its address;
+// if nil the client dials the address over the network.
+func (c *Client) SetDialer(dialer Dialer) {
+	c.dialer = dialer
+}
+
+// SetUnaryInterceptors specifies the chain of interceptors that wrap unary
+// requests sent with gRPC, including the admin rpcs; the first interceptor is
+// the outermost. Only the grpc transport uses interceptors.
+func (c *Client) SetUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) {
+	c.unary = interceptors
+}
+
+// SetStreamInterceptors specifies the chain of interceptors that wrap the
+// streaming rpcs sent with gRPC; the first interceptor is the outermost.
+func (c *Client) SetStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) {
+	c.streams = interceptors
+}
+
+// SetKeepalive specifies how often the client pings the server on idle gRPC
+// connections and how long it waits for a ping to be acknowledged before
+// closing the connection. gRPC pings at most every 10 seconds, and the server
+// must permit pings that often, e.g. with its own keepalive. If the interval
+// is zero the client does not ping the server.
+func (c *Client) SetKeepalive(interval, timeout time.Duration) {
+	c.keepalive = interval
+	c.pingWait = timeout
+}
+
+// SetMaxMessageSize specifies the largest message in bytes the client receives
+// or sends with gRPC; if zero the gRPC defaults are used.
+func (c *Client) SetMaxMessageSize(size int) {
+	c.maxSize = size
+}
+
+// SetToken specifies the token the client presents with each request to a
+// server that requires authentication, either as a bearer token or by signing
+// the request with the token; if empty no credentials are sent. Only the grpc
+// transport supports tokens.
+func (c *Client) SetToken(token string, scheme AuthScheme) {
+	c.token = token
+	c.scheme = scheme
+}
+
+// Connect to the server; the timeout is used both to dial the server and as
+// the deadline for each individual request sent on the connection.
 func (c *Client) Connect(timeout time.Duration) (err error) {
-	if c.conn, err = grpc.Dial(c.addr, grpc.WithInsecure(), grpc.WithTimeout(timeout)); err != nil {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	c.timeout = timeout
+	if c.conn, err = c.transport.Dial(c, timeout); err != nil {
 		return WrapError("could not connect to '%s'", err, c.addr)
 	}
 
-	c.stream = pb.NewHelloClient(c.conn)
+	// The streaming and admin rpcs are only available on grpc connections
+	c.stream, c.admin, c.health = nil, nil, nil
+	if conn, ok := c.conn.(*grpcConn); ok {
+		c.stream = conn.client
+		c.admin = pb.NewAdminClient(conn.cc)
+		c.health = healthpb.NewHealthClient(conn.cc)
+	}
 	return nil
 }
 
 func (c *Client) Close() (err error) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
 	if c.conn == nil {
 		return nil
 	}
 
+	c.echoMu.Lock()
+	c.closeEcho()
+	c.echoMu.Unlock()
+
 	if err = c.conn.Close(); err != nil {
 		return WrapError("couldn't close connection", err)
 	}
 
 	c.conn = nil
 	c.stream = nil
+	c.admin = nil
+	c.health = nil
 	return nil
 }
 
@@ -74,20 +280,291 @@ func (c *Client) Shutdown() error {
 	return c.Close()
 }
 
+// Send a message to the server, retrying according to the retry policy if
+// the message fails. An error is returned if all attempts are exhausted.
 func (c *Client) Send(msg string) error {
+	req := c.message(msg)
+	return c.do(func(ctx context.Context, conn Conn, _ pb.HelloClient) error {
+		c.count(&c.nSent, 1)
+		reply, err := conn.Respond(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		c.count(&c.nBytes, uint64(proto.Size(req)))
+		c.received(reply)
+		info("received: %s\n", reply.String())
+		return c.check(req, reply)
+	})
+}
+
+// Stream sends a message to the server and receives n replies on a server
+// stream. The per-request deadline applies to the entire stream.
+func (c *Client) Stream(msg string, n int) error {
+	if err := c.streaming(); err != nil {
+		return err
+	}
+
+	req := c.message(msg)
+	req.Repeat = uint32(n)
+
+	return c.do(func(ctx context.Context, _ Conn, client pb.HelloClient) error {
+		c.count(&c.nSent, 1)
+		stream, err := client.Stream(ctx, req)
+		if err != nil {
+			return err
+		}
+		c.count(&c.nBytes, uint64(proto.Size(req)))
+
+		for {
+			reply, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			c.received(reply)
+			trace("received: %s\n", reply.String())
+			if err := c.check(req, reply); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// Collect sends all of the messages to the server on a client stream then
+// waits for a single reply. The per-request deadline applies to the stream.
+func (c *Client) Collect(msgs []string) error {
+	if err := c.streaming(); err != nil {
+		return err
+	}
+
+	return c.do(func(ctx context.Context, _ Conn, client pb.HelloClient) error {
+		stream, err := client.Collect(ctx)
+		if err != nil {
+			return err
+		}
+
+		var req *pb.BasicMessage
+		for _, msg := range msgs {
+			req = c.message(msg)
 
+			// io.EOF means the server closed the stream, the error is on recv
+			if err := stream.Send(req); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+			c.count(&c.nSent, 1)
+			c.count(&c.nBytes, uint64(proto.Size(req)))
+		}
+
+		reply, err := stream.CloseAndRecv()
+		if err != nil {
+			return err
+		}
+
+		// The server replies to the last message on the stream
+		c.received(reply)
+		info("received: %s\n", reply.String())
+		return c.check(req, reply)
+	})
+}
+
+// Echo sends a message to the server on a bidirectional stream and waits for
+// the reply. The stream is opened on the first message and kept open for
+// subsequent messages; if the per-request deadline passes before the reply is
+// received, the stream is canceled and reopened by the next message.
+// Messages sent concurrently are serialized on the stream.
+func (c *Client) Echo(msg string) error {
+	if err := c.streaming(); err != nil {
+		return err
+	}
+
+	req := c.message(msg)
+	return c.do(func(ctx context.Context, _ Conn, client pb.HelloClient) (err error) {
+		c.echoMu.Lock()
+		defer c.echoMu.Unlock()
+
+		if c.echo == nil {
+			var echoCtx context.Context
+			echoCtx, c.echoCancel = context.WithCancel(context.Background())
+			if c.echo, err = client.Echo(echoCtx); err != nil {
+				c.closeEcho()
+				return err
+			}
+		}
+
+		// If send fails with io.EOF the stream error is returned by recv
+		c.count(&c.nSent, 1)
+		if err = c.echo.Send(req); err != nil && err != io.EOF {
+			c.closeEcho()
+			return err
+		}
+
+		reply, err := c.recvEcho(ctx)
+		if err != nil {
+			c.closeEcho()
+			return err
+		}
+
+		c.count(&c.nBytes, uint64(proto.Size(req)))
+		c.received(reply)
+		info("received: %s\n", reply.String())
+		return c.check(req, reply)
+	})
+}
+
+// recvEcho receives a reply on the bidirectional stream, canceling the stream if
+// the context is done first. The echo lock must be held.
+func (c *Client) recvEcho(ctx context.Context) (*pb.BasicMessage, error) {
+	if ctx.Done() == nil {
+		return c.echo.Recv()
+	}
+
+	var (
+		reply  *pb.BasicMessage
+		err    error
+		stream = c.echo
+		done   = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		reply, err = stream.Recv()
+	}()
+
+	select {
+	case <-done:
+		return reply, err
+	case <-ctx.Done():
+		// Canceling the stream ends the receive so that it does not outlive the call
+		c.echoCancel()
+		<-done
+		return nil, contextError(ctx.Err())
+	}
+}
+
+// message creates a request with a payload and requested reply size that are
+// generated by the payload sizes of the client.
+func (c *Client) message(msg string) *pb.BasicMessage {
 	req := &pb.BasicMessage{
 		Sender:  c.identity,
 		Message: msg,
 	}
 
-	c.nSent++
-	reply, err := c.stream.Respond(context.Background(), req)
-	if err != nil {
-		return WrapError("could not send message", err)
+	if c.size != nil {
+		req.Payload = Payload(c.size.Next())
+	}
+
+	if c.replySize != nil {
+		req.ReplySize = uint32(c.replySize.Next())
+	}
+
+	return req
+}
+
+// received counts a reply from the server.
+func (c *Client) received(reply *pb.BasicMessage) {
+	c.count(&c.nRecv, 1)
+	c.count(&c.nBytesRecv, uint64(proto.Size(reply)))
+}
+
+// check that the reply echoes the request if the client verifies replies.
+func (c *Client) check(req, reply *pb.BasicMessage) error {
+	if !c.verify || req == nil {
+		return nil
+	}
+
+	if reply.Message != req.Message || !bytes.Equal(reply.Payload, req.Payload) {
+		return ErrIntegrity
+	}
+	return nil
+}
+
+// do makes an rpc on the current connection with the per-request deadline,
+// retrying according to the retry policy if the rpc fails. An error is
+// returned if all attempts fail.
+func (c *Client) do(rpc func(ctx context.Context, conn Conn, client pb.HelloClient) error) (err error) {
+	c.connMu.RLock()
+	conn, client, timeout := c.conn, c.stream, c.timeout
+	c.connMu.RUnlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	send := func(ctx context.Context) error {
+		return rpc(ctx, conn, client)
 	}
 
-	c.nRecv++
-	info("received: %s\n", reply.String())
+	for attempt := 1; ; attempt++ {
+		if err = c.call(timeout, send); err == nil {
+			return nil
+		}
+
+		if !c.retries.Retry(attempt, err) {
+			return WrapError("could not send message after %d attempt(s)", err, attempt)
+		}
+
+		backoff := c.retries.Backoff(attempt)
+		debug("retrying message in %s: %s", backoff, err)
+
+		c.count(&c.nRetries, 1)
+		time.Sleep(backoff)
+	}
+}
+
+// streaming returns an error if the transport does not support streaming rpcs.
+func (c *Client) streaming() error {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+
+	if c.conn != nil && c.stream == nil {
+		return WrapError("the %s transport does not support streaming", nil, c.transport)
+	}
 	return nil
 }
+
+// call makes a single attempt of the rpc with the per-request deadline.
+func (c *Client) call(timeout time.Duration, rpc func(ctx context.Context) error) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return rpc(ctx)
+}
+
+// count adds n to the counter if the current time is in the measured phase.
+func (c *Client) count(counter *uint64, n uint64) {
+	if c.measuring(time.Now()) {
+		atomic.AddUint64(counter, n)
+	}
+}
+
+// measuring returns true if the time is in the measured phase of a benchmark,
+// which is always true if the client does not have warm-up or cool-down phases.
+func (c *Client) measuring(t time.Time) bool {
+	from, to := atomic.LoadInt64(&c.phaseFrom), atomic.LoadInt64(&c.phaseTo)
+	nsec := t.UnixNano()
+	return (from == 0 || nsec >= from) && (to == 0 || nsec < to)
+}
+
+// closeEcho closes the bidirectional stream so that it is reopened on the next
+// message sent with Echo; the echo lock must be held.
+func (c *Client) closeEcho() {
+	if c.echo != nil {
+		c.echo.CloseSend()
+		c.echo = nil
+	}
+
+	if c.echoCancel != nil {
+		c.echoCancel()
+		c.echoCancel = nil
+	}
+}
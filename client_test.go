@@ -0,0 +1,266 @@
+package echo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// connect a client with the transport to a server running the echo reply
+// strategy, returning a function that closes the client and stops the server.
+func connect(t *testing.T, transport Transport) (*Server, *Client, func()) {
+	s, _ := NewServer("", "test")
+	s.SetTransport(transport)
+	s.SetReplyStrategy(EchoReply)
+	addr, stop := runServer(t, s)
+
+	c, _ := NewClient(addr, "test")
+	c.SetTransport(transport)
+	c.SetVerify(true)
+	if err := c.Connect(5 * time.Second); err != nil {
+		stop()
+		t.Fatalf("could not connect: %s", err)
+	}
+
+	return s, c, func() {
+		c.Close()
+		stop()
+	}
+}
+
+// hammer calls send concurrently from many go routines with unique messages.
+func hammer(t *testing.T, send func(msg string) error) {
+	var wg sync.WaitGroup
+	for i := 0; i < nWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < nMessages; j++ {
+				if err := send(fmt.Sprintf("msg %d from %d", j, i)); err != nil {
+					t.Errorf("could not send message: %s", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSendConcurrent(t *testing.T) {
+	for _, name := range Transports() {
+		t.Run(name, func(t *testing.T) {
+			transport, _ := ParseTransport(name)
+			s, c, stop := connect(t, transport)
+			defer stop()
+			hammer(t, c.Send)
+
+			total := uint64(nWorkers * nMessages)
+			if n := atomic.LoadUint64(&c.nSent); n != total {
+				t.Errorf("expected %d messages sent, got %d", total, n)
+			}
+
+			if n := atomic.LoadUint64(&c.nRecv); n != total {
+				t.Errorf("expected %d replies received, got %d", total, n)
+			}
+
+			if n := s.metrics.Accesses(); n != total {
+				t.Errorf("expected %d server accesses, got %d", total, n)
+			}
+		})
+	}
+}
+
+func TestEchoConcurrent(t *testing.T) {
+	// Replies are verified, so they must be paired with their requests
+	s, c, stop := connect(t, GRPCTransport{})
+	defer stop()
+	hammer(t, c.Echo)
+
+	total := uint64(nWorkers * nMessages)
+	if n := atomic.LoadUint64(&c.nRecv); n != total {
+		t.Errorf("expected %d replies received, got %d", total, n)
+	}
+
+	if n := s.metrics.Accesses(); n != total {
+		t.Errorf("expected %d server accesses, got %d", total, n)
+	}
+}
+
+func TestEchoDeadline(t *testing.T) {
+	s, c, stop := connect(t, GRPCTransport{})
+	defer stop()
+
+	c.connMu.Lock()
+	c.timeout = 50 * time.Millisecond
+	c.connMu.Unlock()
+
+	if err := c.Echo("hello"); err != nil {
+		t.Fatalf("could not echo message: %s", err)
+	}
+
+	// A hung reply ends at the per-request deadline and counts as a timeout
+	s.SetFaults(Faults{HangRate: 1})
+	c.mode = BidiStream
+	c.reset(time.Now())
+
+	errc := make(chan error, 1)
+	go func() { errc <- c.access(time.Now()) }()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("could not access server: %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("echo did not return when the deadline passed")
+	}
+
+	if c.nTimeout != 1 || c.messages != 0 {
+		t.Errorf("expected 1 timeout and no messages, got %d timeouts and %d messages", c.nTimeout, c.messages)
+	}
+
+	// The stream is reopened for the next message
+	s.SetFaults(Faults{})
+	if err := c.Echo("hello"); err != nil {
+		t.Errorf("expected echo to succeed on a new stream, got %s", err)
+	}
+}
+
+func TestCloseConcurrent(t *testing.T) {
+	_, c, stop := connect(t, GRPCTransport{})
+	defer stop()
+
+	// Sending on a closed client must fail cleanly rather than panic
+	var wg sync.WaitGroup
+	for i := 0; i < nWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < nMessages; j++ {
+				if err := c.Send("hello"); err == ErrNotConnected {
+					return
+				}
+			}
+		}()
+	}
+
+	c.Close()
+	wg.Wait()
+
+	if err := c.Send("hello"); err != ErrNotConnected {
+		t.Errorf("expected ErrNotConnected after close, got %v", err)
+	}
+}
+
+func TestBenchmarkRun(t *testing.T) {
+	s, _ := NewServer("", "test")
+	addr, stop := runServer(t, s)
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, _ := NewBenchmark(addr, "test", 4)
+	b.SetInterval(50 * time.Millisecond)
+	b.SetPhases(50*time.Millisecond, 50*time.Millisecond)
+	if err := b.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer b.Close()
+
+	if err := b.Run(200*time.Millisecond, filepath.Join(dir, "results.json")); err != nil {
+		t.Fatalf("could not run benchmark: %s", err)
+	}
+
+	var messages uint64
+	for _, client := range b.clients {
+		client.mu.Lock()
+		messages += client.messages
+		client.mu.Unlock()
+	}
+
+	if messages == 0 {
+		t.Error("no messages were measured")
+	}
+
+	// Messages are sent during the warm-up and cool-down but not measured
+	if n := s.metrics.Accesses(); n <= messages {
+		t.Errorf("expected more server accesses than the %d measured messages, got %d", messages, n)
+	}
+}
+
+func TestBenchmarkUnreachable(t *testing.T) {
+	// Listen to find a free port then close it so that nothing is listening
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	addr := sock.Addr().String()
+	sock.Close()
+
+	dir, err := ioutil.TempDir("", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, _ := NewBenchmark(addr, "test", 2)
+	if err := b.Connect(time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer b.Close()
+
+	path := filepath.Join(dir, "results.json")
+	if err := b.Run(100*time.Millisecond, path); err == nil {
+		t.Error("expected benchmark of an unreachable server to fail")
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected no results to be written for an unreachable server")
+	}
+}
+
+func TestBenchmarkDuration(t *testing.T) {
+	b, _ := NewBenchmark("localhost:4157", "test", 1)
+	b.SetPhases(10*time.Millisecond, 10*time.Millisecond)
+
+	for _, duration := range []time.Duration{0, -time.Second} {
+		if err := b.Run(duration, ""); err == nil {
+			t.Errorf("expected benchmark with a duration of %s to be rejected", duration)
+		}
+	}
+}
+
+func TestBenchmarkSweepProgress(t *testing.T) {
+	s, _ := NewServer("", "test")
+	addr, stop := runServer(t, s)
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each run must stop reporting progress before the next resets the series
+	b, _ := NewBenchmark(addr, "test", 2)
+	b.SetInterval(5 * time.Millisecond)
+	b.SetProgress(ioutil.Discard)
+	if err := b.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer b.Close()
+
+	if err := b.Sweep([]int{8, 64, 512}, 50*time.Millisecond, filepath.Join(dir, "results.json")); err != nil {
+		t.Fatalf("could not sweep benchmark: %s", err)
+	}
+}
@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/bbengfort/echo"
 	"github.com/joho/godotenv"
 	"github.com/urfave/cli"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 //===========================================================================
@@ -23,11 +33,29 @@ func main() {
 	// Instantiate the command line application
 	app := cli.NewApp()
 	app.Name = "echgo"
-	app.Version = "0.1"
+	app.Version = echo.Version
 	app.Usage = "run gRPC echo server and client"
 
 	// Define commands available to the application
 	app.Commands = []cli.Command{
+		{
+			Name:     "certs",
+			Usage:    "generate a local certificate authority and tls certificates",
+			Category: "server",
+			Action:   certs,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "o, outdir",
+					Usage: "directory to write the certificates and keys to",
+					Value: "certs",
+				},
+				cli.StringFlag{
+					Name:  "H, hosts",
+					Usage: "comma separated names and ips the server certificate is valid for",
+					Value: "localhost,127.0.0.1,::1",
+				},
+			},
+		},
 		{
 			Name:     "serve",
 			Usage:    "run the echo server",
@@ -39,6 +67,11 @@ func main() {
 					Usage: "address to bind the server to",
 					Value: ":4157",
 				},
+				cli.StringFlag{
+					Name:  "transport",
+					Usage: "protocol to send messages with: grpc, tcp, http, or rpc",
+					Value: echo.DefaultTransport,
+				},
 				cli.StringFlag{
 					Name:  "n, name",
 					Usage: "name to identify the server (default is hostname)",
@@ -52,6 +85,308 @@ func main() {
 					Usage: "path to write metrics out to",
 					Value: "metrics.json",
 				},
+				cli.StringFlag{
+					Name:  "reply",
+					Usage: "reply strategy: fixed, fixed:text, echo, reverse, upper, or size:4K",
+					Value: echo.DefaultReply,
+				},
+				cli.StringFlag{
+					Name:  "interval",
+					Usage: "parsable duration of the windows of the throughput time series",
+				},
+				cli.BoolFlag{
+					Name:  "progress",
+					Usage: "print a summary of each interval to stdout while running",
+				},
+				cli.StringFlag{
+					Name:  "metrics-addr",
+					Usage: "address to serve prometheus metrics on at /metrics, e.g. :9090",
+				},
+				cli.StringFlag{
+					Name:  "admin-addr",
+					Usage: "address to serve the admin rpcs on with grpc, e.g. :4158",
+				},
+				cli.BoolFlag{
+					Name:  "access-log",
+					Usage: "log every grpc request handled by the server",
+				},
+				cli.StringFlag{
+					Name:  "delay",
+					Usage: "delay added to requests: fixed (10ms), range (5ms-20ms), normal:mean,stddev or exp:mean",
+				},
+				cli.StringFlag{
+					Name:  "errors",
+					Usage: "percent of requests that fail with the error code, e.g. 5%",
+				},
+				cli.StringFlag{
+					Name:  "error-code",
+					Usage: "gRPC status code of the failed requests",
+					Value: echo.DefaultFaultCode.String(),
+				},
+				cli.StringFlag{
+					Name:  "hangs",
+					Usage: "percent of requests that hang until their deadline, e.g. 1%",
+				},
+				cli.StringFlag{
+					Name:  "drain",
+					Usage: "parsable duration to wait for in-flight requests on shutdown",
+					Value: echo.DefaultDrainTimeout.String(),
+				},
+				cli.StringFlag{
+					Name:  "cert",
+					Usage: "path to the tls certificate",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "path to the tls private key",
+				},
+				cli.StringFlag{
+					Name:  "ca",
+					Usage: "path to the certificate authority to verify clients with (mutual tls)",
+				},
+				cli.StringFlag{
+					Name:  "tokens",
+					Usage: "path to a file of tokens clients must present, one per line; comma separated tokens are also read from $" + echo.TokensEnv,
+				},
+				cli.UintFlag{
+					Name:  "verbosity",
+					Usage: "set log level from 0-4, lower is more verbose",
+					Value: 3,
+				},
+			},
+		},
+		{
+			Name:     "status",
+			Usage:    "print a live snapshot of the server's metrics",
+			Category: "admin",
+			Action:   serverStatus,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "a, addr",
+					Usage: "address of the grpc server or of its admin rpcs",
+					Value: "localhost:4157",
+				},
+				cli.StringFlag{
+					Name:  "t, timeout",
+					Usage: "timeout for the status request",
+					Value: "5s",
+				},
+				cli.BoolFlag{
+					Name:  "j, json",
+					Usage: "print the status as json rather than a table",
+				},
+				cli.StringFlag{
+					Name:  "cert",
+					Usage: "path to the tls certificate",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "path to the tls private key",
+				},
+				cli.StringFlag{
+					Name:  "ca",
+					Usage: "path to the certificate authority to verify the server with",
+				},
+				cli.StringFlag{
+					Name:   "token",
+					Usage:  "token to authenticate with the server",
+					EnvVar: "ECHO_TOKEN",
+				},
+				cli.BoolFlag{
+					Name:  "hmac",
+					Usage: "sign requests with the token rather than sending it",
+				},
+			},
+		},
+		{
+			Name:     "reset",
+			Usage:    "flush the server's metrics to its outpath and start a fresh run",
+			Category: "admin",
+			Action:   resetMetrics,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "a, addr",
+					Usage: "address of the grpc server or of its admin rpcs",
+					Value: "localhost:4157",
+				},
+				cli.StringFlag{
+					Name:  "l, label",
+					Usage: "label to identify the run of the flushed metrics",
+				},
+				cli.StringFlag{
+					Name:  "t, timeout",
+					Usage: "timeout for the reset request",
+					Value: "5s",
+				},
+				cli.StringFlag{
+					Name:  "cert",
+					Usage: "path to the tls certificate",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "path to the tls private key",
+				},
+				cli.StringFlag{
+					Name:  "ca",
+					Usage: "path to the certificate authority to verify the server with",
+				},
+				cli.StringFlag{
+					Name:   "token",
+					Usage:  "token to authenticate with the server",
+					EnvVar: "ECHO_TOKEN",
+				},
+				cli.BoolFlag{
+					Name:  "hmac",
+					Usage: "sign requests with the token rather than sending it",
+				},
+			},
+		},
+		{
+			Name:     "health",
+			Usage:    "check if the server is serving, exits non-zero if it is not",
+			Category: "admin",
+			Action:   health,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "a, addr",
+					Usage: "address of the grpc server or of its admin rpcs",
+					Value: "localhost:4157",
+				},
+				cli.StringFlag{
+					Name:  "s, service",
+					Usage: "name of the service to check (default is the whole server)",
+				},
+				cli.StringFlag{
+					Name:  "t, timeout",
+					Usage: "timeout for the health check",
+					Value: "5s",
+				},
+				cli.StringFlag{
+					Name:  "cert",
+					Usage: "path to the tls certificate",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "path to the tls private key",
+				},
+				cli.StringFlag{
+					Name:  "ca",
+					Usage: "path to the certificate authority to verify the server with",
+				},
+			},
+		},
+		{
+			Name:     "faults",
+			Usage:    "replace the faults the server injects, no flags stops injecting faults",
+			Category: "admin",
+			Action:   injectFaults,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "a, addr",
+					Usage: "address of the grpc server or of its admin rpcs",
+					Value: "localhost:4157",
+				},
+				cli.StringFlag{
+					Name:  "delay",
+					Usage: "delay added to requests: fixed (10ms), range (5ms-20ms), normal:mean,stddev or exp:mean",
+				},
+				cli.StringFlag{
+					Name:  "errors",
+					Usage: "percent of requests that fail with the error code, e.g. 5%",
+				},
+				cli.StringFlag{
+					Name:  "error-code",
+					Usage: "gRPC status code of the failed requests",
+					Value: echo.DefaultFaultCode.String(),
+				},
+				cli.StringFlag{
+					Name:  "hangs",
+					Usage: "percent of requests that hang until their deadline, e.g. 1%",
+				},
+				cli.StringFlag{
+					Name:  "t, timeout",
+					Usage: "timeout for the faults request",
+					Value: "5s",
+				},
+				cli.StringFlag{
+					Name:  "cert",
+					Usage: "path to the tls certificate",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "path to the tls private key",
+				},
+				cli.StringFlag{
+					Name:  "ca",
+					Usage: "path to the certificate authority to verify the server with",
+				},
+				cli.StringFlag{
+					Name:   "token",
+					Usage:  "token to authenticate with the server",
+					EnvVar: "ECHO_TOKEN",
+				},
+				cli.BoolFlag{
+					Name:  "hmac",
+					Usage: "sign requests with the token rather than sending it",
+				},
+			},
+		},
+		{
+			Name:     "proxy",
+			Usage:    "forward tcp connections to the server over an impaired network",
+			Category: "server",
+			Action:   proxy,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "l, listen",
+					Usage: "address to accept client connections on",
+					Value: ":4159",
+				},
+				cli.StringFlag{
+					Name:  "upstream",
+					Usage: "address of the server to forward connections to",
+					Value: "localhost:4157",
+				},
+				cli.StringFlag{
+					Name:  "latency",
+					Usage: "parsable duration added to data in each direction",
+					Value: "0s",
+				},
+				cli.StringFlag{
+					Name:  "jitter",
+					Usage: "parsable duration of the maximum random variation of the latency",
+					Value: "0s",
+				},
+				cli.StringFlag{
+					Name:  "bandwidth",
+					Usage: "bytes per second in each direction, e.g. 1M/s (default is unlimited)",
+				},
+				cli.StringFlag{
+					Name:  "stalls",
+					Usage: "percent of chunks of data that are held before forwarding, e.g. 1%",
+				},
+				cli.StringFlag{
+					Name:  "stall",
+					Usage: "parsable duration that stalled data is held for",
+					Value: echo.DefaultStall.String(),
+				},
+				cli.StringFlag{
+					Name:  "resets",
+					Usage: "percent of chunks of data that reset the connection, e.g. 0.1%",
+				},
+				cli.StringFlag{
+					Name:  "u, uptime",
+					Usage: "pass a parsable duration to shut the proxy down after",
+				},
+				cli.StringFlag{
+					Name:  "o, outpath",
+					Usage: "path to write the proxy counters to",
+				},
+				cli.Int64Flag{
+					Name:  "s, seed",
+					Usage: "specify random seed for reproducible impairment",
+					Value: time.Now().Unix(),
+				},
 				cli.UintFlag{
 					Name:  "verbosity",
 					Usage: "set log level from 0-4, lower is more verbose",
@@ -70,6 +405,11 @@ func main() {
 					Usage: "address to connect to the server on",
 					Value: "localhost:4157",
 				},
+				cli.StringFlag{
+					Name:  "transport",
+					Usage: "protocol to send messages with: grpc, tcp, http, or rpc",
+					Value: echo.DefaultTransport,
+				},
 				cli.StringFlag{
 					Name:  "n, name",
 					Usage: "name to identify the client (default is hostname)",
@@ -84,6 +424,41 @@ func main() {
 					Usage: "number of retries before quitting",
 					Value: 3,
 				},
+				cli.StringFlag{
+					Name:  "b, backoff",
+					Usage: "parsable duration to wait before the first retry",
+					Value: echo.DefaultInitialBackoff.String(),
+				},
+				cli.StringFlag{
+					Name:  "retry-codes",
+					Usage: "comma separated gRPC status codes that can be retried",
+					Value: "unavailable,resource_exhausted,aborted",
+				},
+				cli.BoolFlag{
+					Name:  "verify",
+					Usage: "check that replies echo requests (server must use echo replies)",
+				},
+				cli.StringFlag{
+					Name:  "cert",
+					Usage: "path to the tls certificate",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "path to the tls private key",
+				},
+				cli.StringFlag{
+					Name:  "ca",
+					Usage: "path to the certificate authority to verify the server with",
+				},
+				cli.StringFlag{
+					Name:   "token",
+					Usage:  "token to authenticate with the server",
+					EnvVar: "ECHO_TOKEN",
+				},
+				cli.BoolFlag{
+					Name:  "hmac",
+					Usage: "sign requests with the token rather than sending it",
+				},
 			},
 		},
 		{
@@ -97,6 +472,11 @@ func main() {
 					Usage: "address to connect to the server on",
 					Value: "localhost:4157",
 				},
+				cli.StringFlag{
+					Name:  "transport",
+					Usage: "protocol to send messages with: grpc, tcp, http, or rpc",
+					Value: echo.DefaultTransport,
+				},
 				cli.StringFlag{
 					Name:  "n, name",
 					Usage: "name to identify the server (default is hostname)",
@@ -106,6 +486,16 @@ func main() {
 					Usage: "parsable duration of the benchmark",
 					Value: "30s",
 				},
+				cli.StringFlag{
+					Name:  "warmup",
+					Usage: "parsable duration to send messages before measuring",
+					Value: "0s",
+				},
+				cli.StringFlag{
+					Name:  "cooldown",
+					Usage: "parsable duration to send messages after measuring",
+					Value: "0s",
+				},
 				cli.StringFlag{
 					Name:  "t, timeout",
 					Usage: "recv timeout for each message",
@@ -116,15 +506,101 @@ func main() {
 					Usage: "number of retries before quitting",
 					Value: 3,
 				},
+				cli.StringFlag{
+					Name:  "b, backoff",
+					Usage: "parsable duration to wait before the first retry",
+					Value: echo.DefaultInitialBackoff.String(),
+				},
+				cli.StringFlag{
+					Name:  "retry-codes",
+					Usage: "comma separated gRPC status codes that can be retried",
+					Value: "unavailable,resource_exhausted,aborted",
+				},
+				cli.BoolFlag{
+					Name:  "verify",
+					Usage: "check that replies echo requests (server must use echo replies)",
+				},
+				cli.StringFlag{
+					Name:  "cert",
+					Usage: "path to the tls certificate",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "path to the tls private key",
+				},
+				cli.StringFlag{
+					Name:  "ca",
+					Usage: "path to the certificate authority to verify the server with",
+				},
+				cli.StringFlag{
+					Name:   "token",
+					Usage:  "token to authenticate with the server",
+					EnvVar: "ECHO_TOKEN",
+				},
+				cli.BoolFlag{
+					Name:  "hmac",
+					Usage: "sign requests with the token rather than sending it",
+				},
 				cli.IntFlag{
 					Name:  "c, clients",
-					Usage: "extra information: number of clients",
+					Usage: "number of concurrent clients to run",
+					Value: 1,
+				},
+				cli.StringFlag{
+					Name:  "m, mode",
+					Usage: "rpc to benchmark: unary, server-stream, client-stream, or bidi",
+					Value: echo.Unary.String(),
+				},
+				cli.IntFlag{
+					Name:  "batch",
+					Usage: "number of messages per rpc in the streaming modes",
+					Value: echo.DefaultBatch,
+				},
+				cli.StringFlag{
+					Name:  "size",
+					Usage: "request payload size: fixed (1K), range (64-4K), normal:mean,stddev or exp:mean",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "reply-size",
+					Usage: "reply payload size using the same specification as size",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "rate",
+					Usage: "total open-loop message rate, e.g. 5000/s, rpcs are sent at the rate divided by the batch in the streaming modes (default is closed-loop)",
+				},
+				cli.StringFlag{
+					Name:  "arrivals",
+					Usage: "open-loop request schedule: constant or poisson",
+					Value: echo.ConstantArrivals.String(),
+				},
+				cli.IntFlag{
+					Name:  "outstanding",
+					Usage: "maximum open-loop requests in-flight per client before dropping",
+					Value: echo.DefaultOutstanding,
+				},
+				cli.StringFlag{
+					Name:  "sweep",
+					Usage: "comma separated request sizes to run the benchmark for in turn",
+				},
+				cli.StringFlag{
+					Name:  "interval",
+					Usage: "parsable duration of the windows of the throughput time series",
+				},
+				cli.BoolFlag{
+					Name:  "progress",
+					Usage: "print a summary of each interval to stdout while running",
 				},
 				cli.StringFlag{
 					Name:  "o, results",
 					Usage: "path to write the results to",
 					Value: "results.json",
 				},
+				cli.StringFlag{
+					Name:  "histogram",
+					Usage: "path to write the raw latency histograms to for plotting",
+				},
 				cli.Int64Flag{
 					Name:  "s, seed",
 					Usage: "specify random seed for the process",
@@ -168,26 +644,350 @@ func serve(c *cli.Context) error {
 		return exit("could not initialize server", err)
 	}
 
-	// Defer the shutdown
-	defer server.Shutdown(c.String("outpath"))
+	replies, err := echo.ParseReply(c.String("reply"))
+	if err != nil {
+		return exit("could not parse reply strategy", err)
+	}
+	server.SetReplyStrategy(replies)
+
+	transport, err := echo.ParseTransport(c.String("transport"))
+	if err != nil {
+		return exit("", err)
+	}
+	server.SetTransport(transport)
+
+	// A certificate authority without a certificate would serve insecure connections
+	cert, key, ca := c.String("cert"), c.String("key"), c.String("ca")
+	if ca != "" && (cert == "" || key == "") {
+		return exit("", echo.WrapError("--ca requires --cert and --key to verify client certificates", nil))
+	}
 
-	// If uptime is specified, set a fixed duration for the server to run.
-	if uptime := c.String("uptime"); uptime != "" {
-		d, err := time.ParseDuration(uptime)
+	if cert != "" || key != "" {
+		creds, err := echo.ServerCredentials(cert, key, ca)
 		if err != nil {
-			return exit("could not parse uptime", err)
+			return exit("could not load tls credentials", err)
 		}
+		server.SetCredentials(creds)
+	}
+
+	server.SetMetricsAddr(c.String("metrics-addr"))
+	server.SetAdminAddr(c.String("admin-addr"))
+	server.SetOutpath(c.String("outpath"))
 
-		time.AfterFunc(d, func() {
-			server.Shutdown(c.String("outpath"))
-			os.Exit(0)
-		})
+	tokens := echo.TokensFromEnv()
+	if path := c.String("tokens"); path != "" {
+		loaded, err := echo.LoadTokens(path)
+		if err != nil {
+			return exit("", err)
+		}
+		tokens = append(tokens, loaded...)
 	}
+	server.SetTokens(tokens...)
 
-	// Run the network server and broadcast clients
-	if err := server.Run(); err != nil {
+	// Recover from panics in handlers rather than crashing the benchmark
+	unary := []grpc.UnaryServerInterceptor{echo.UnaryRecovery}
+	streams := []grpc.StreamServerInterceptor{echo.StreamRecovery}
+	if c.Bool("access-log") {
+		unary = append([]grpc.UnaryServerInterceptor{echo.UnaryLogger}, unary...)
+		streams = append([]grpc.StreamServerInterceptor{echo.StreamLogger}, streams...)
+	}
+	server.SetUnaryInterceptors(unary...)
+	server.SetStreamInterceptors(streams...)
+
+	interval, err := parseInterval(c)
+	if err != nil {
+		return exit("could not parse interval", err)
+	}
+	server.SetInterval(interval)
+	if c.Bool("progress") {
+		server.SetProgress(os.Stdout)
+	}
+
+	faults, err := parseFaults(c)
+	if err != nil {
+		return exit("could not parse faults", err)
+	}
+
+	if faults != (echo.Faults{}) {
+		if err = server.SetFaults(faults); err != nil {
+			return exit("", err)
+		}
+	}
+
+	drain, err := time.ParseDuration(c.String("drain"))
+	if err != nil {
+		return exit("could not parse drain timeout", err)
+	}
+	server.SetDrainTimeout(drain)
+
+	// Stop the server when interrupted, terminated or after the uptime
+	ctx, cancel, err := interrupt(c)
+	if err != nil {
+		return exit("could not parse uptime", err)
+	}
+	defer cancel()
+
+	// Run the network server until it is stopped, then flush the metrics
+	if err := server.Run(ctx); err != nil {
+		server.Shutdown(c.String("outpath"))
 		return exit("could not run server", err)
 	}
+
+	if err := server.Shutdown(c.String("outpath")); err != nil {
+		return exit("could not write metrics", err)
+	}
+	return nil
+}
+
+func proxy(c *cli.Context) error {
+	// Set the debug log level and the random seed for reproducible impairment
+	echo.SetLogLevel(uint8(c.Uint("verbosity")))
+	rand.Seed(c.Int64("seed"))
+
+	var (
+		impair echo.Impairment
+		err    error
+	)
+
+	if impair.Latency, err = time.ParseDuration(c.String("latency")); err != nil {
+		return exit("could not parse latency", err)
+	}
+	if impair.Jitter, err = time.ParseDuration(c.String("jitter")); err != nil {
+		return exit("could not parse jitter", err)
+	}
+	if impair.Bandwidth, err = echo.ParseBandwidth(c.String("bandwidth")); err != nil {
+		return exit("could not parse bandwidth", err)
+	}
+	if impair.StallRate, err = echo.ParsePercent(c.String("stalls")); err != nil {
+		return exit("could not parse stalls", err)
+	}
+	if impair.Stall, err = time.ParseDuration(c.String("stall")); err != nil {
+		return exit("could not parse stall", err)
+	}
+	if impair.ResetRate, err = echo.ParsePercent(c.String("resets")); err != nil {
+		return exit("could not parse resets", err)
+	}
+
+	proxy, err := echo.NewProxy(c.String("listen"), c.String("upstream"), impair)
+	if err != nil {
+		return exit("could not create proxy", err)
+	}
+
+	// Stop the proxy when interrupted, terminated or after the uptime
+	ctx, cancel, err := interrupt(c)
+	if err != nil {
+		return exit("could not parse uptime", err)
+	}
+	defer cancel()
+
+	if err = proxy.Run(ctx); err != nil {
+		return exit("could not run proxy", err)
+	}
+
+	fmt.Println(proxy)
+	if err = proxy.Write(c.String("outpath")); err != nil {
+		return exit("could not write proxy counters", err)
+	}
+	return nil
+}
+
+// Helper to create a context that is canceled when the process is interrupted
+// or terminated, or after the uptime if specified by the command line flags.
+func interrupt(c *cli.Context) (context.Context, context.CancelFunc, error) {
+	var uptime time.Duration
+	if s := c.String("uptime"); s != "" {
+		var err error
+		if uptime, err = time.ParseDuration(s); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	if uptime > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), uptime)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		defer signal.Stop(sigs)
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel, nil
+}
+
+func certs(c *cli.Context) error {
+	hosts := strings.Split(c.String("hosts"), ",")
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+
+	if err := echo.GenerateCerts(c.String("outdir"), hosts); err != nil {
+		return exit("could not generate certificates", err)
+	}
+
+	fmt.Printf("certificates for %s written to %s\n", strings.Join(hosts, ", "), c.String("outdir"))
+	return nil
+}
+
+//===========================================================================
+// Admin Commands
+//===========================================================================
+
+func serverStatus(c *cli.Context) error {
+	echo.SetLogLevel(echo.Warn)
+	client, err := admin(c)
+	if err != nil {
+		return exit("", err)
+	}
+	defer client.Close()
+
+	reply, err := client.Status()
+	if err != nil {
+		return exit("", err)
+	}
+
+	uptime := time.Duration(reply.Uptime)
+	if c.Bool("json") {
+		data := map[string]interface{}{
+			"name":                 reply.Name,
+			"version":              reply.Version,
+			"transport":            reply.Transport,
+			"log level":            reply.LogLevel,
+			"uptime":               uptime.String(),
+			"accesses":             reply.Accesses,
+			"clients":              reply.Clients,
+			"throughput (msg/sec)": reply.Throughput,
+			"in flight":            reply.InFlight,
+			"errors":               reply.Errors,
+			"expired":              reply.Expired,
+			"faults":               reply.Faults,
+		}
+		return printJSON(data)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "name\t%s\n", reply.Name)
+	fmt.Fprintf(w, "version\t%s\n", reply.Version)
+	fmt.Fprintf(w, "transport\t%s\n", reply.Transport)
+	fmt.Fprintf(w, "log level\t%s\n", reply.LogLevel)
+	fmt.Fprintf(w, "uptime\t%s\n", uptime.Round(time.Millisecond))
+	fmt.Fprintf(w, "accesses\t%d\n", reply.Accesses)
+	fmt.Fprintf(w, "clients\t%d\n", reply.Clients)
+	fmt.Fprintf(w, "throughput\t%0.3f msg/sec\n", reply.Throughput)
+	fmt.Fprintf(w, "in flight\t%d\n", reply.InFlight)
+	fmt.Fprintf(w, "errors\t%d\n", reply.Errors)
+	fmt.Fprintf(w, "expired\t%d\n", reply.Expired)
+	fmt.Fprintf(w, "faults\t%s\n", reply.Faults)
+	return w.Flush()
+}
+
+func resetMetrics(c *cli.Context) error {
+	echo.SetLogLevel(echo.Warn)
+	client, err := admin(c)
+	if err != nil {
+		return exit("", err)
+	}
+	defer client.Close()
+
+	reply, err := client.Reset(c.String("label"))
+	if err != nil {
+		return exit("", err)
+	}
+
+	fmt.Printf(
+		"flushed run %q: %d accesses by %d clients in %s -- %0.4f accesses/second\n",
+		reply.Label, reply.Accesses, reply.Clients, time.Duration(reply.Duration), reply.Throughput,
+	)
+	return nil
+}
+
+func health(c *cli.Context) error {
+	echo.SetLogLevel(echo.Warn)
+	client, err := admin(c)
+	if err != nil {
+		return exit("", err)
+	}
+	defer client.Close()
+
+	status, err := client.Health(c.String("service"))
+	if err != nil {
+		return exit("", err)
+	}
+
+	if status != healthpb.HealthCheckResponse_SERVING {
+		return cli.NewExitError(status.String(), 1)
+	}
+
+	fmt.Println(status)
+	return nil
+}
+
+func injectFaults(c *cli.Context) error {
+	faults, err := parseFaults(c)
+	if err != nil {
+		return exit("could not parse faults", err)
+	}
+
+	echo.SetLogLevel(echo.Warn)
+	client, err := admin(c)
+	if err != nil {
+		return exit("", err)
+	}
+	defer client.Close()
+
+	if faults, err = client.Inject(faults); err != nil {
+		return exit("", err)
+	}
+
+	fmt.Printf("injecting %s\n", faults)
+	return nil
+}
+
+// Helper to connect a grpc client to the server for the admin rpcs.
+func admin(c *cli.Context) (*echo.Client, error) {
+	client, err := echo.NewClient(c.String("addr"), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if timeout, err = time.ParseDuration(c.String("timeout")); err != nil {
+		return nil, err
+	}
+
+	var creds credentials.TransportCredentials
+	if creds, err = clientCredentials(c); err != nil {
+		return nil, err
+	}
+	client.SetCredentials(creds)
+	client.SetToken(clientToken(c))
+
+	if err = client.Connect(timeout); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Helper to print the data as indented json to stdout.
+func printJSON(data interface{}) error {
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return exit("could not marshal json", err)
+	}
+	fmt.Println(string(out))
 	return nil
 }
 
@@ -207,13 +1007,33 @@ func send(c *cli.Context) error {
 		return exit("", err)
 	}
 
+	var retries *echo.RetryPolicy
+	if retries, err = retryPolicy(c); err != nil {
+		return exit("", err)
+	}
+	client.SetRetryPolicy(retries)
+	client.SetVerify(c.Bool("verify"))
+
+	var transport echo.Transport
+	if transport, err = echo.ParseTransport(c.String("transport")); err != nil {
+		return exit("", err)
+	}
+	client.SetTransport(transport)
+
+	var creds credentials.TransportCredentials
+	if creds, err = clientCredentials(c); err != nil {
+		return exit("could not load tls credentials", err)
+	}
+	client.SetCredentials(creds)
+	client.SetToken(clientToken(c))
+
 	if err = client.Connect(timeout); err != nil {
 		return exit("", err)
 	}
 
 	for _, msg := range c.Args() {
 		if err := client.Send(msg); err != nil {
-			exit("", err)
+			return exit("", err)
 		}
 	}
 
@@ -229,30 +1049,186 @@ func bench(c *cli.Context) error {
 	// Set the random seed
 	rand.Seed(c.Int64("seed"))
 
-	client, err := echo.NewClient(c.String("addr"), c.String("name"))
+	benchmark, err := echo.NewBenchmark(c.String("addr"), c.String("name"), c.Int("clients"))
 	if err != nil {
-		return exit("could not create client", err)
+		return exit("could not create benchmark", err)
 	}
-	defer client.Shutdown()
 
 	var duration time.Duration
 	if duration, err = time.ParseDuration(c.String("duration")); err != nil {
 		return exit("", err)
 	}
 
+	var warmup, cooldown time.Duration
+	if warmup, err = time.ParseDuration(c.String("warmup")); err != nil {
+		return exit("could not parse warmup", err)
+	}
+	if cooldown, err = time.ParseDuration(c.String("cooldown")); err != nil {
+		return exit("could not parse cooldown", err)
+	}
+	benchmark.SetPhases(warmup, cooldown)
+
 	var timeout time.Duration
 	if timeout, err = time.ParseDuration(c.String("timeout")); err != nil {
 		return exit("", err)
 	}
 
-	if err = client.Connect(timeout); err != nil {
+	var retries *echo.RetryPolicy
+	if retries, err = retryPolicy(c); err != nil {
 		return exit("", err)
 	}
-	defer client.Close()
+	benchmark.SetRetryPolicy(retries)
+	benchmark.SetVerify(c.Bool("verify"))
+
+	var transport echo.Transport
+	if transport, err = echo.ParseTransport(c.String("transport")); err != nil {
+		return exit("", err)
+	}
+	benchmark.SetTransport(transport)
+
+	var creds credentials.TransportCredentials
+	if creds, err = clientCredentials(c); err != nil {
+		return exit("could not load tls credentials", err)
+	}
+	benchmark.SetCredentials(creds)
+	benchmark.SetToken(clientToken(c))
+
+	var mode echo.Mode
+	if mode, err = echo.ParseMode(c.String("mode")); err != nil {
+		return exit("", err)
+	}
+	benchmark.SetMode(mode, c.Int("batch"))
+
+	if err = benchmark.Connect(timeout); err != nil {
+		return exit("", err)
+	}
+	defer benchmark.Close()
+
+	var size, replySize echo.PayloadSize
+	if size, err = echo.ParsePayloadSize(c.String("size")); err != nil {
+		return exit("", err)
+	}
+	if replySize, err = echo.ParsePayloadSize(c.String("reply-size")); err != nil {
+		return exit("", err)
+	}
+	benchmark.SetPayload(size, replySize)
+
+	if rate := c.String("rate"); rate != "" {
+		var (
+			perSecond float64
+			arrivals  echo.Arrivals
+		)
+
+		if perSecond, err = echo.ParseRate(rate); err != nil {
+			return exit("", err)
+		}
+		if arrivals, err = echo.ParseArrivals(c.String("arrivals")); err != nil {
+			return exit("", err)
+		}
+		benchmark.SetRate(perSecond, arrivals, c.Int("outstanding"))
+	}
+
+	var interval time.Duration
+	if interval, err = parseInterval(c); err != nil {
+		return exit("could not parse interval", err)
+	}
+	benchmark.SetInterval(interval)
+	if c.Bool("progress") {
+		benchmark.SetProgress(os.Stdout)
+	}
 
-	nClients := c.Int("clients")
-	// retries := c.Int("retries")
 	results := c.String("results")
+	benchmark.SetHistogramPath(c.String("histogram"))
+
+	if sweep := c.String("sweep"); sweep != "" {
+		var sizes []int
+		if sizes, err = echo.ParseSizes(sweep); err != nil {
+			return exit("could not parse sweep", err)
+		}
+
+		if err = benchmark.Sweep(sizes, duration, results); err != nil {
+			return exit("", err)
+		}
+		return nil
+	}
+
+	if err = benchmark.Run(duration, results); err != nil {
+		return exit("", err)
+	}
+	return nil
+}
 
-	return client.Benchmark(duration, results, nClients)
+// Helper to create the client retry policy from the command line flags.
+func retryPolicy(c *cli.Context) (policy *echo.RetryPolicy, err error) {
+	policy = echo.NewRetryPolicy(c.Int("retries"))
+	if policy.InitialBackoff, err = time.ParseDuration(c.String("backoff")); err != nil {
+		return nil, err
+	}
+
+	if policy.Retryable, err = echo.ParseCodes(c.String("retry-codes")); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Helper to parse the faults injected by the server from the command line flags.
+func parseFaults(c *cli.Context) (faults echo.Faults, err error) {
+	if faults.Delay, err = echo.ParseDelay(c.String("delay")); err != nil {
+		return faults, err
+	}
+
+	if faults.ErrorRate, err = echo.ParsePercent(c.String("errors")); err != nil {
+		return faults, err
+	}
+
+	if faults.HangRate, err = echo.ParsePercent(c.String("hangs")); err != nil {
+		return faults, err
+	}
+
+	if faults.ErrorRate > 0 {
+		var parsed []codes.Code
+		if parsed, err = echo.ParseCodes(c.String("error-code")); err != nil {
+			return faults, err
+		}
+
+		if len(parsed) != 1 {
+			return faults, fmt.Errorf("specify a single error code, not %q", c.String("error-code"))
+		}
+		faults.ErrorCode = parsed[0]
+	}
+
+	return faults, faults.Validate()
+}
+
+// Helper to parse the time series interval from the command line flags, the
+// default interval is used if progress is requested without an interval.
+func parseInterval(c *cli.Context) (time.Duration, error) {
+	if interval := c.String("interval"); interval != "" {
+		return time.ParseDuration(interval)
+	}
+
+	if c.Bool("progress") {
+		return echo.DefaultInterval, nil
+	}
+	return 0, nil
+}
+
+// Helper to get the token the client authenticates with from the command line
+// flags and how it is presented to the server.
+func clientToken(c *cli.Context) (string, echo.AuthScheme) {
+	if c.Bool("hmac") {
+		return c.String("token"), echo.HMACAuth
+	}
+	return c.String("token"), echo.BearerAuth
+}
+
+// Helper to create the client tls credentials from the command line flags,
+// returns nil credentials if neither a certificate nor a ca are specified.
+func clientCredentials(c *cli.Context) (credentials.TransportCredentials, error) {
+	cert, key, ca := c.String("cert"), c.String("key"), c.String("ca")
+	if cert == "" && key == "" && ca == "" {
+		return nil, nil
+	}
+	return echo.ClientCredentials(cert, key, ca, "")
 }
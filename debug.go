@@ -4,7 +4,9 @@ package echo
 
 import (
 	"log"
+	"os"
 	"strings"
+	"sync"
 )
 
 // Levels for implementing the debug and trace message functionality.
@@ -20,6 +22,7 @@ const (
 // These variables are initialized in init()
 var logLevel = Debug
 var logger *log.Logger
+var logMu sync.RWMutex // guards the level and logger, which may be set while logging
 var logLevelStrings = [...]string{"trace", "debug", "info", "status", "warn", "silent"}
 
 //===========================================================================
@@ -28,6 +31,8 @@ var logLevelStrings = [...]string{"trace", "debug", "info", "status", "warn", "s
 
 // LogLevel returns a string representation of the current level
 func LogLevel() string {
+	logMu.RLock()
+	defer logMu.RUnlock()
 	return logLevelStrings[logLevel]
 }
 
@@ -38,7 +43,22 @@ func SetLogLevel(level uint8) {
 		level = Silent
 	}
 
+	logMu.Lock()
 	logLevel = level
+	logMu.Unlock()
+}
+
+// SetLogger specifies the logger that messages are written to; if nil the
+// messages are written to stdout with the echo prefix. The logger is shared
+// by all servers and clients in the process and is safe to set concurrently.
+func SetLogger(l *log.Logger) {
+	if l == nil {
+		l = log.New(os.Stdout, "[echo] ", log.Lmicroseconds)
+	}
+
+	logMu.Lock()
+	logger = l
+	logMu.Unlock()
 }
 
 //===========================================================================
@@ -48,12 +68,16 @@ func SetLogLevel(level uint8) {
 // Print to the standard logger at the specified level. Arguments are handled
 // in the manner of log.Printf, but a newline is appended.
 func print(level uint8, msg string, a ...interface{}) {
-	if level >= logLevel {
+	logMu.RLock()
+	l, enabled := logger, level >= logLevel
+	logMu.RUnlock()
+
+	if enabled {
 		if !strings.HasSuffix(msg, "\n") {
 			msg += "\n"
 		}
 
-		logger.Printf(msg, a...)
+		l.Printf(msg, a...)
 	}
 }
 
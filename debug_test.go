@@ -0,0 +1,48 @@
+package echo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"sync"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	logMu.RLock()
+	level := logLevel
+	logMu.RUnlock()
+
+	buf := new(bytes.Buffer)
+	defer SetLogger(nil)
+	defer SetLogLevel(level)
+
+	SetLogger(log.New(buf, "[test] ", 0))
+	SetLogLevel(Status)
+
+	status("hello from %s", "test")
+	debug("not written at the status level")
+	if buf.String() != "[test] hello from test\n" {
+		t.Errorf("expected message to be written to the logger, got %q", buf.String())
+	}
+
+	// The logger and level may be set while other goroutines are logging
+	SetLogger(log.New(ioutil.Discard, "", 0))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			SetLogger(log.New(ioutil.Discard, "", 0))
+		}()
+		go func() {
+			defer wg.Done()
+			SetLogLevel(Debug)
+		}()
+		go func() {
+			defer wg.Done()
+			status("hello from %s", "goroutine")
+		}()
+	}
+	wg.Wait()
+}
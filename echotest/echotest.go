@@ -0,0 +1,142 @@
+/*
+Package echotest provides a harness that serves an echo server on an in-memory
+listener so that tests can connect clients and run benchmarks against it
+without binding to real ports. Services that embed echo servers or clients can
+use the harness in their own integration tests.
+*/
+package echotest
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"time"
+
+	"github.com/bbengfort/echo"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Addr is the address of the in-memory server that harness clients dial.
+const Addr = "bufconn"
+
+// BufferSize is the number of bytes buffered in each direction of an in-memory
+// connection before writes block.
+const BufferSize = 1024 * 1024
+
+// DialTimeout is how long harness clients wait to connect to the server.
+const DialTimeout = 5 * time.Second
+
+// Harness serves an echo server on an in-memory listener until it is closed.
+// Clients created by the harness dial the listener instead of the network and
+// use the transport of the server.
+type Harness struct {
+	Server *echo.Server       // the server handling requests in memory
+	sock   *bufconn.Listener  // the in-memory listener the server is bound to
+	cancel context.CancelFunc // stops the server
+	errc   chan error         // returns the error from serving when stopped
+}
+
+// New serves the server on an in-memory listener until the harness is closed.
+// The server should be configured, e.g. with a transport or reply strategy,
+// before it is passed to the harness. If the server is nil, a server with the
+// default reply strategy and transport is created.
+func New(s *echo.Server) (*Harness, error) {
+	if s == nil {
+		var err error
+		if s, err = echo.NewServer(Addr, "echotest"); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Harness{
+		Server: s,
+		sock:   bufconn.Listen(BufferSize),
+		cancel: cancel,
+		errc:   make(chan error, 1),
+	}
+
+	go func() {
+		h.errc <- s.Serve(ctx, h.sock)
+	}()
+
+	return h, nil
+}
+
+// Dial connects to the in-memory server, ignoring the address and timeout.
+// It can be passed to the SetDialer method of clients and benchmarks.
+func (h *Harness) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return h.sock.Dial()
+}
+
+// Client returns a client with the name that is connected to the server. The
+// client can still be configured for sending messages or benchmarking and
+// should be closed before the harness is.
+func (h *Harness) Client(name string) (*echo.Client, error) {
+	c, err := echo.NewClient(Addr, name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetTransport(h.Server.Transport())
+	c.SetDialer(h.Dial)
+	if err = c.Connect(DialTimeout); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Benchmark returns a benchmark with n clients connected to the server. The
+// benchmark can still be configured before it is run and should be closed
+// before the harness is.
+func (h *Harness) Benchmark(n int) (*echo.Benchmark, error) {
+	b, err := echo.NewBenchmark(Addr, "echotest", n)
+	if err != nil {
+		return nil, err
+	}
+
+	b.SetTransport(h.Server.Transport())
+	b.SetDialer(h.Dial)
+	if err = b.Connect(DialTimeout); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Metrics returns the statistics of the requests handled by the server.
+func (h *Harness) Metrics() *echo.Metrics {
+	return h.Server.Metrics()
+}
+
+// Close stops the server, waiting for in-flight requests to drain, and returns
+// any error from serving. Connections that are still open when the harness is
+// closed are only closed after the server's drain timeout.
+func (h *Harness) Close() error {
+	h.cancel()
+	return <-h.errc
+}
+
+// ReadResults reads the JSON results appended to the path by benchmarks or by
+// server shutdown, returning one map of results per line.
+func ReadResults(path string) ([]map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		result := make(map[string]interface{})
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, scanner.Err()
+}
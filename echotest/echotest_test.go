@@ -0,0 +1,109 @@
+package echotest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/echo"
+)
+
+func TestMain(m *testing.M) {
+	echo.SetLogLevel(echo.Silent)
+	os.Exit(m.Run())
+}
+
+func TestHarness(t *testing.T) {
+	for _, name := range echo.Transports() {
+		t.Run(name, func(t *testing.T) {
+			transport, _ := echo.ParseTransport(name)
+			s, _ := echo.NewServer(Addr, "echotest")
+			s.SetTransport(transport)
+			s.SetReplyStrategy(echo.EchoReply)
+
+			h, err := New(s)
+			if err != nil {
+				t.Fatalf("could not create harness: %s", err)
+			}
+
+			c, err := h.Client("test")
+			if err != nil {
+				t.Fatalf("could not connect client: %s", err)
+			}
+
+			// Replies that do not echo the request fail verification
+			c.SetVerify(true)
+			for i := 0; i < 100; i++ {
+				if err := c.Send(fmt.Sprintf("message %d", i)); err != nil {
+					t.Fatalf("could not send message: %s", err)
+				}
+			}
+
+			if err := c.Close(); err != nil {
+				t.Errorf("could not close client: %s", err)
+			}
+
+			if err := h.Close(); err != nil {
+				t.Errorf("server stopped with error: %s", err)
+			}
+
+			if n := h.Metrics().Accesses(); n != 100 {
+				t.Errorf("expected 100 accesses, got %d", n)
+			}
+
+			if n := h.Metrics().NClients(); n != 1 {
+				t.Errorf("expected 1 client, got %d", n)
+			}
+		})
+	}
+}
+
+func TestHarnessBenchmark(t *testing.T) {
+	h, err := New(nil)
+	if err != nil {
+		t.Fatalf("could not create harness: %s", err)
+	}
+	defer h.Close()
+
+	dir, err := ioutil.TempDir("", "echotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.json")
+
+	b, err := h.Benchmark(4)
+	if err != nil {
+		t.Fatalf("could not connect benchmark: %s", err)
+	}
+	defer b.Close()
+
+	if err := b.Run(100*time.Millisecond, path); err != nil {
+		t.Fatalf("could not run benchmark: %s", err)
+	}
+
+	results, err := ReadResults(path)
+	if err != nil {
+		t.Fatalf("could not read results: %s", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	messages, _ := results[0]["messages"].(float64)
+	if messages == 0 {
+		t.Error("no messages were recorded in the results")
+	}
+
+	if n := h.Metrics().Accesses(); float64(n) < messages {
+		t.Errorf("expected at least %0.0f server accesses, got %d", messages, n)
+	}
+
+	if clients, _ := results[0]["n_clients"].(float64); clients != 4 {
+		t.Errorf("expected 4 clients in the results, got %0.0f", clients)
+	}
+}
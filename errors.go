@@ -12,6 +12,9 @@ import (
 // Standard errors for primary operations.
 var (
 	ErrNotImplemented = errors.New("functionality not implemented yet")
+	ErrNotConnected   = errors.New("client is not connected to the server")
+	ErrServerRunning  = errors.New("server is already running")
+	ErrIntegrity      = errors.New("reply does not echo the request")
 )
 
 //===========================================================================
@@ -41,6 +44,11 @@ func (e *Error) Error() string {
 	return e.msg
 }
 
+// Unwrap returns the wrapped error if any
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
 // String returns the error message
 func (e *Error) String() string {
 	return e.Error()
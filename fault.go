@@ -0,0 +1,323 @@
+package echo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// Kinds of faults injected by the server, counted in the metrics.
+const (
+	DelayFault = "delay"
+	ErrorFault = "error"
+	HangFault  = "hang"
+)
+
+// DefaultFaultCode is the status code of injected errors if none is specified.
+const DefaultFaultCode = codes.Unavailable
+
+// Faults specifies the failures the server injects into its replies so that
+// the resilience of clients can be exercised. The zero value injects no faults.
+type Faults struct {
+	Delay     Delay      // generates the delay added to every request, nil for none
+	ErrorRate float64    // fraction of requests that fail with the error code
+	ErrorCode codes.Code // status code of the injected errors
+	HangRate  float64    // fraction of requests that hang until their deadline
+}
+
+// Validate returns an error if the rates are not fractions or if errors are
+// injected without a failing status code.
+func (f Faults) Validate() error {
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("error rate %v is not between 0 and 1", f.ErrorRate)
+	}
+
+	if f.HangRate < 0 || f.HangRate > 1 {
+		return fmt.Errorf("hang rate %v is not between 0 and 1", f.HangRate)
+	}
+
+	if f.ErrorRate > 0 && f.ErrorCode == codes.OK {
+		return fmt.Errorf("injected errors require a status code other than %s", codes.OK)
+	}
+
+	return nil
+}
+
+// String returns a summary of the faults that are injected.
+func (f Faults) String() string {
+	var faults []string
+	if f.Delay != nil {
+		faults = append(faults, fmt.Sprintf("%s delay", f.Delay))
+	}
+
+	if f.ErrorRate > 0 {
+		faults = append(faults, fmt.Sprintf("%s %s errors", formatPercent(f.ErrorRate), f.ErrorCode))
+	}
+
+	if f.HangRate > 0 {
+		faults = append(faults, fmt.Sprintf("%s hangs", formatPercent(f.HangRate)))
+	}
+
+	if len(faults) == 0 {
+		return "no faults"
+	}
+	return strings.Join(faults, ", ")
+}
+
+// Helper to convert the faults to a protocol buffer message.
+func (f Faults) proto() *pb.Faults {
+	msg := &pb.Faults{
+		ErrorRate: f.ErrorRate,
+		ErrorCode: uint32(f.ErrorCode),
+		HangRate:  f.HangRate,
+	}
+
+	if f.Delay != nil {
+		msg.Delay = f.Delay.String()
+	}
+	return msg
+}
+
+// Helper to parse the faults from a protocol buffer message.
+func parseFaults(msg *pb.Faults) (faults Faults, err error) {
+	if faults.Delay, err = ParseDelay(msg.Delay); err != nil {
+		return faults, err
+	}
+
+	faults.ErrorRate = msg.ErrorRate
+	faults.ErrorCode = codes.Code(msg.ErrorCode)
+	faults.HangRate = msg.HangRate
+	return faults, faults.Validate()
+}
+
+// inject the faults into the handling of a request, returning an error if the
+// request should fail. Delays and hangs end early with an error if the context
+// is done or if the server is stopped.
+func (s *Server) inject(ctx context.Context, sender string) error {
+	s.mu.Lock()
+	faults, halt := s.faults, s.halt
+	s.mu.Unlock()
+
+	if faults.Delay != nil {
+		if delay := faults.Delay.Next(); delay > 0 {
+			s.metrics.Inject(DelayFault)
+			trace("delaying request from %s by %s", sender, delay)
+
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return contextError(ctx.Err())
+			case <-halt:
+				return gstatus.Error(codes.Unavailable, "server is stopping")
+			}
+		}
+	}
+
+	if faults.HangRate > 0 && rand.Float64() < faults.HangRate {
+		s.metrics.Inject(HangFault)
+		debug("hanging request from %s", sender)
+
+		select {
+		case <-ctx.Done():
+			return contextError(ctx.Err())
+		case <-halt:
+			return gstatus.Error(codes.Unavailable, "server is stopping")
+		}
+	}
+
+	if faults.ErrorRate > 0 && rand.Float64() < faults.ErrorRate {
+		s.metrics.Inject(ErrorFault)
+		debug("failing request from %s with %s", sender, faults.ErrorCode)
+		return gstatus.Error(faults.ErrorCode, "injected fault")
+	}
+
+	return nil
+}
+
+// Helper to convert a context error into a status error.
+func contextError(err error) error {
+	code := codes.DeadlineExceeded
+	if err == context.Canceled {
+		code = codes.Canceled
+	}
+	return gstatus.Error(code, err.Error())
+}
+
+//===========================================================================
+// Delay Generators
+//===========================================================================
+
+// Delay generates the durations that requests are delayed by.
+type Delay interface {
+	Next() time.Duration // the delay of the next request
+	String() string      // the specification of the delay
+}
+
+// ParseDelay parses a delay specification, which is one of:
+//
+//	10ms            fixed delay as a parsable duration
+//	5ms-20ms        uniformly distributed between min and max inclusive
+//	normal:10ms,2ms normally distributed with mean and standard deviation
+//	exp:10ms        exponentially distributed with the mean delay
+//
+// An empty or zero specification means requests are not delayed.
+func ParseDelay(spec string) (Delay, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	// Distributions are specified as name:arg,arg
+	if parts := strings.SplitN(spec, ":", 2); len(parts) == 2 {
+		args, err := parseDurations(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(parts[0]) {
+		case "normal":
+			if len(args) != 2 {
+				return nil, fmt.Errorf("normal delay requires mean and stddev: %q", spec)
+			}
+			return &normalDelay{mean: args[0], stddev: args[1]}, nil
+		case "exp":
+			if len(args) != 1 {
+				return nil, fmt.Errorf("exp delay requires a mean: %q", spec)
+			}
+			return &expDelay{mean: args[0]}, nil
+		default:
+			return nil, fmt.Errorf("unknown delay distribution %q", parts[0])
+		}
+	}
+
+	// Uniform ranges are specified as min-max
+	if parts := strings.SplitN(spec, "-", 2); len(parts) == 2 {
+		args, err := parseDurations(strings.Join(parts, ","))
+		if err != nil {
+			return nil, err
+		}
+
+		if args[1] < args[0] {
+			return nil, fmt.Errorf("delay range %q has max less than min", spec)
+		}
+		return &uniformDelay{min: args[0], max: args[1]}, nil
+	}
+
+	delay, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if delay <= 0 {
+		return nil, nil
+	}
+	return FixedDelay(delay), nil
+}
+
+// ParsePercent parses a percentage, e.g. 5%, or a fraction, e.g. 0.05, and
+// returns the fraction between 0 and 1.
+func ParsePercent(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	scale := 1.0
+	if strings.HasSuffix(s, "%") {
+		scale = 100.0
+		s = strings.TrimSuffix(s, "%")
+	}
+
+	fraction, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse percent %q", s)
+	}
+
+	fraction /= scale
+	if fraction < 0 || fraction > 1 {
+		return 0, fmt.Errorf("percent %q is not between 0%% and 100%%", s)
+	}
+	return fraction, nil
+}
+
+// Helper to parse a comma separated list of durations.
+func parseDurations(s string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, d)
+	}
+	return durations, nil
+}
+
+// Helper to format a fraction as a percentage.
+func formatPercent(fraction float64) string {
+	return strconv.FormatFloat(fraction*100, 'f', -1, 64) + "%"
+}
+
+// FixedDelay delays every request by the same duration.
+type FixedDelay time.Duration
+
+// Next returns the fixed delay.
+func (d FixedDelay) Next() time.Duration {
+	return time.Duration(d)
+}
+
+// String returns the fixed delay as a parsable duration.
+func (d FixedDelay) String() string {
+	return time.Duration(d).String()
+}
+
+// uniformDelay generates delays uniformly in a range.
+type uniformDelay struct {
+	min, max time.Duration
+}
+
+func (d *uniformDelay) Next() time.Duration {
+	return d.min + time.Duration(rand.Int63n(int64(d.max-d.min)+1))
+}
+
+func (d *uniformDelay) String() string {
+	return fmt.Sprintf("%s-%s", d.min, d.max)
+}
+
+// normalDelay generates normally distributed delays, never less than 0.
+type normalDelay struct {
+	mean, stddev time.Duration
+}
+
+func (d *normalDelay) Next() time.Duration {
+	delay := rand.NormFloat64()*float64(d.stddev) + float64(d.mean)
+	return time.Duration(math.Max(0, delay))
+}
+
+func (d *normalDelay) String() string {
+	return fmt.Sprintf("normal:%s,%s", d.mean, d.stddev)
+}
+
+// expDelay generates exponentially distributed delays.
+type expDelay struct {
+	mean time.Duration
+}
+
+func (d *expDelay) Next() time.Duration {
+	return time.Duration(rand.ExpFloat64() * float64(d.mean))
+}
+
+func (d *expDelay) String() string {
+	return fmt.Sprintf("exp:%s", d.mean)
+}
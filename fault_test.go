@@ -0,0 +1,128 @@
+package echo
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+func TestParseDelay(t *testing.T) {
+	for spec, expected := range map[string]string{
+		"10ms":            "10ms",
+		"5ms-20ms":        "5ms-20ms",
+		"normal:10ms,2ms": "normal:10ms,2ms",
+		"exp:1s":          "exp:1s",
+	} {
+		delay, err := ParseDelay(spec)
+		if err != nil {
+			t.Errorf("could not parse %q: %s", spec, err)
+			continue
+		}
+
+		if delay.String() != expected {
+			t.Errorf("expected %q to parse as %q, got %q", spec, expected, delay)
+		}
+	}
+
+	for _, spec := range []string{"", "0s"} {
+		if delay, err := ParseDelay(spec); err != nil || delay != nil {
+			t.Errorf("expected %q to be no delay, got %v (%v)", spec, delay, err)
+		}
+	}
+
+	for _, spec := range []string{"foo", "20ms-5ms", "normal:10ms", "gamma:1s"} {
+		if _, err := ParseDelay(spec); err == nil {
+			t.Errorf("expected %q to fail to parse", spec)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	for spec, expected := range map[string]float64{"5%": 0.05, "0.25": 0.25, "100%": 1, "": 0} {
+		fraction, err := ParsePercent(spec)
+		if err != nil || fraction != expected {
+			t.Errorf("expected %q to parse as %v, got %v (%v)", spec, expected, fraction, err)
+		}
+	}
+
+	for _, spec := range []string{"150%", "2", "-1%", "five"} {
+		if _, err := ParsePercent(spec); err == nil {
+			t.Errorf("expected %q to fail to parse", spec)
+		}
+	}
+}
+
+func TestInjectFaults(t *testing.T) {
+	s, _ := NewServer("", "test")
+	in := &pb.BasicMessage{Sender: "test"}
+
+	// Every request fails with the error code
+	s.SetFaults(Faults{ErrorRate: 1, ErrorCode: codes.ResourceExhausted})
+	if _, err := s.Respond(context.Background(), in); gstatus.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected injected resource exhausted error, got %v", err)
+	}
+
+	// Every request is delayed
+	s.SetFaults(Faults{Delay: FixedDelay(20 * time.Millisecond)})
+	start := time.Now()
+	if _, err := s.Respond(context.Background(), in); err != nil {
+		t.Errorf("expected delayed request to succeed, got %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected request to be delayed by 20ms, took %s", elapsed)
+	}
+
+	// Every request hangs until its deadline
+	s.SetFaults(Faults{HangRate: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Respond(ctx, in); gstatus.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected hung request to exceed its deadline, got %v", err)
+	}
+
+	for _, fault := range []string{ErrorFault, DelayFault, HangFault} {
+		if n := s.metrics.Injected(fault); n != 1 {
+			t.Errorf("expected 1 %s fault to be injected, got %d", fault, n)
+		}
+	}
+
+	if n := s.metrics.Errors(); n != 2 {
+		t.Errorf("expected 2 failed requests, got %d", n)
+	}
+
+	if err := s.SetFaults(Faults{ErrorRate: 0.5}); err == nil {
+		t.Error("expected errors without a status code to be invalid")
+	}
+}
+
+func TestInjectRPC(t *testing.T) {
+	s, c, stop := connect(t, GRPCTransport{})
+	defer stop()
+
+	faults, err := c.Inject(Faults{Delay: FixedDelay(time.Millisecond), ErrorRate: 1, ErrorCode: codes.Aborted})
+	if err != nil {
+		t.Fatalf("could not inject faults: %s", err)
+	}
+
+	if faults.String() != s.Faults().String() {
+		t.Errorf("expected injected faults %q, server has %q", faults, s.Faults())
+	}
+
+	if err := c.Send("hello"); err == nil || statusCode(err) != codes.Aborted {
+		t.Errorf("expected injected aborted error, got %v", err)
+	}
+
+	// The zero value stops injecting faults
+	if _, err := c.Inject(Faults{}); err != nil {
+		t.Fatalf("could not clear faults: %s", err)
+	}
+
+	if err := c.Send("hello"); err != nil {
+		t.Errorf("expected request to succeed once faults are cleared, got %s", err)
+	}
+}
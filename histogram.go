@@ -0,0 +1,232 @@
+package echo
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Histograms keep values to three significant figures by dividing every power
+// of two into 1024 linear sub-buckets; values less than 2048 are exact.
+const (
+	subBucketBits      = 11
+	subBucketCount     = 1 << subBucketBits
+	subBucketHalfCount = subBucketCount / 2
+)
+
+// Percentiles reported in the summary of a histogram.
+var percentiles = [...]struct {
+	name string
+	q    float64
+}{
+	{"p50", 50}, {"p90", 90}, {"p99", 99}, {"p99.9", 99.9},
+}
+
+// NewHistogram creates an empty histogram.
+func NewHistogram() *Histogram {
+	return new(Histogram)
+}
+
+// Histogram is a high dynamic range histogram of non-negative integer values
+// such as latencies in nanoseconds. Values are recorded in buckets whose width
+// grows with the magnitude of the value so that the histogram has a constant
+// relative precision over the full range with a small memory footprint.
+// Histograms from multiple sources can be merged without loss of precision.
+type Histogram struct {
+	sync.RWMutex
+	counts []uint64 // the count of values in each bucket, grown on demand
+	total  uint64   // the number of values recorded
+	sum    float64  // the sum of values recorded, for the mean
+	min    int64    // the smallest value recorded
+	max    int64    // the largest value recorded
+}
+
+// HistogramBucket is the count of the values recorded in a single bucket of a
+// histogram, identified by the largest value equivalent to the bucket.
+type HistogramBucket struct {
+	Value int64  `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+// Record a value in the histogram, negative values are recorded as zero.
+func (h *Histogram) Record(value int64) {
+	if value < 0 {
+		value = 0
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	idx := bucketIndex(value)
+	if idx >= len(h.counts) {
+		counts := make([]uint64, idx+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+
+	h.counts[idx]++
+	if h.total == 0 || value < h.min {
+		h.min = value
+	}
+	if value > h.max {
+		h.max = value
+	}
+	h.total++
+	h.sum += float64(value)
+}
+
+// RecordDuration records a duration in nanoseconds.
+func (h *Histogram) RecordDuration(d time.Duration) {
+	h.Record(d.Nanoseconds())
+}
+
+// Merge the values recorded by another histogram into this histogram.
+func (h *Histogram) Merge(o *Histogram) {
+	h.Lock()
+	o.RLock()
+	defer h.Unlock()
+	defer o.RUnlock()
+
+	if o.total == 0 {
+		return
+	}
+
+	if len(o.counts) > len(h.counts) {
+		counts := make([]uint64, len(o.counts))
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+
+	for idx, count := range o.counts {
+		h.counts[idx] += count
+	}
+
+	if h.total == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.total += o.total
+	h.sum += o.sum
+}
+
+// Count returns the number of values recorded.
+func (h *Histogram) Count() uint64 {
+	h.RLock()
+	defer h.RUnlock()
+	return h.total
+}
+
+// Min returns the smallest value recorded.
+func (h *Histogram) Min() int64 {
+	h.RLock()
+	defer h.RUnlock()
+	return h.min
+}
+
+// Max returns the largest value recorded.
+func (h *Histogram) Max() int64 {
+	h.RLock()
+	defer h.RUnlock()
+	return h.max
+}
+
+// Mean returns the average of the values recorded.
+func (h *Histogram) Mean() float64 {
+	h.RLock()
+	defer h.RUnlock()
+
+	if h.total == 0 {
+		return 0.0
+	}
+	return h.sum / float64(h.total)
+}
+
+// Percentile returns the value that the specified percentage (0-100] of the
+// recorded values are less than or equal to, within the precision of the
+// histogram. Returns zero if no values have been recorded.
+func (h *Histogram) Percentile(q float64) int64 {
+	h.RLock()
+	defer h.RUnlock()
+
+	if h.total == 0 {
+		return 0
+	}
+
+	if q >= 100 {
+		return h.max
+	}
+
+	target := uint64(math.Ceil(q / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var seen uint64
+	for idx, count := range h.counts {
+		if seen += count; seen >= target {
+			if value := highestEquivalent(idx); value < h.max {
+				return value
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// Buckets returns the non-empty buckets of the histogram for export.
+func (h *Histogram) Buckets() []HistogramBucket {
+	h.RLock()
+	defer h.RUnlock()
+
+	buckets := make([]HistogramBucket, 0)
+	for idx, count := range h.counts {
+		if count > 0 {
+			buckets = append(buckets, HistogramBucket{Value: highestEquivalent(idx), Count: count})
+		}
+	}
+	return buckets
+}
+
+// Serialize the percentiles of the histogram to a map.
+func (h *Histogram) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	data["samples"] = h.Count()
+	data["mean"] = h.Mean()
+	data["min"] = h.Min()
+	data["max"] = h.Max()
+
+	for _, p := range percentiles {
+		data[p.name] = h.Percentile(p.q)
+	}
+
+	return data
+}
+
+// Helper to compute the bucket index of a value.
+func bucketIndex(value int64) int {
+	v := uint64(value)
+	if v < subBucketCount {
+		return int(v)
+	}
+
+	// Keep the top subBucketBits bits of the value: the sub-bucket is in the
+	// upper half of the range [subBucketHalfCount, subBucketCount).
+	shift := bits.Len64(v) - subBucketBits
+	sub := v >> uint(shift)
+	return subBucketCount + (shift-1)*subBucketHalfCount + int(sub-subBucketHalfCount)
+}
+
+// Helper to compute the largest value that is recorded in the bucket index.
+func highestEquivalent(idx int) int64 {
+	if idx < subBucketCount {
+		return int64(idx)
+	}
+
+	idx -= subBucketCount
+	shift := uint(idx/subBucketHalfCount + 1)
+	sub := uint64(idx%subBucketHalfCount + subBucketHalfCount)
+	return int64((sub+1)<<shift - 1)
+}
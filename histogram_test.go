@@ -0,0 +1,110 @@
+package echo
+
+import (
+	"testing"
+)
+
+func TestBucketIndex(t *testing.T) {
+	// Values mapped to their bucket and the largest value equivalent to it
+	for value, expected := range map[int64]struct {
+		idx     int
+		highest int64
+	}{
+		0:    {0, 0},
+		1:    {1, 1},
+		2047: {2047, 2047},
+		2048: {2048, 2049},
+		2049: {2048, 2049},
+		2050: {2049, 2051},
+		4095: {3071, 4095},
+		4096: {3072, 4099},
+		4099: {3072, 4099},
+		4100: {3073, 4103},
+	} {
+		idx := bucketIndex(value)
+		if idx != expected.idx {
+			t.Errorf("expected %d to be in bucket %d, got %d", value, expected.idx, idx)
+			continue
+		}
+
+		if highest := highestEquivalent(idx); highest != expected.highest {
+			t.Errorf("expected highest value of bucket %d to be %d, got %d", idx, expected.highest, highest)
+		}
+	}
+
+	// Buckets are contiguous: the value after the highest of a bucket is in the next
+	for idx := 0; idx < subBucketCount+4*subBucketHalfCount; idx++ {
+		if next := bucketIndex(highestEquivalent(idx) + 1); next != idx+1 {
+			t.Fatalf("expected the value after bucket %d to be in bucket %d, got %d", idx, idx+1, next)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	for n, expected := range map[int64]map[float64]int64{
+		100:   {0: 1, 1: 1, 50: 50, 90: 90, 99: 99, 99.9: 100, 100: 100},
+		10000: {50: 5003, 90: 9007, 99: 9903, 99.9: 9991, 100: 10000},
+	} {
+		h := NewHistogram()
+		for value := int64(1); value <= n; value++ {
+			h.Record(value)
+		}
+
+		for q, value := range expected {
+			if p := h.Percentile(q); p != value {
+				t.Errorf("expected p%v of 1-%d to be %d, got %d", q, n, value, p)
+			}
+		}
+	}
+
+	// Percentiles are capped at the largest value recorded
+	h := NewHistogram()
+	h.Record(4096)
+	if p := h.Percentile(50); p != 4096 {
+		t.Errorf("expected percentile to be the only value recorded, got %d", p)
+	}
+
+	// Negative values are recorded as zero
+	h = NewHistogram()
+	h.Record(-10)
+	if h.Count() != 1 || h.Min() != 0 || h.Max() != 0 {
+		t.Errorf("expected negative value to be recorded as zero, got min %d max %d", h.Min(), h.Max())
+	}
+
+	if p := NewHistogram().Percentile(50); p != 0 {
+		t.Errorf("expected percentile of an empty histogram to be zero, got %d", p)
+	}
+}
+
+func TestMergeHistograms(t *testing.T) {
+	// Merging histograms of the even and odd values is the same as recording all
+	a, b, all := NewHistogram(), NewHistogram(), NewHistogram()
+	for value := int64(1); value <= 5000; value++ {
+		if value%2 == 0 {
+			a.Record(value)
+		} else {
+			b.Record(value * 3)
+		}
+		all.Record(value * (1 + 2*(value%2)))
+	}
+
+	merged := NewHistogram()
+	merged.Merge(a)
+	merged.Merge(b)
+	merged.Merge(NewHistogram())
+
+	if merged.Count() != all.Count() || merged.Min() != all.Min() || merged.Max() != all.Max() || merged.Mean() != all.Mean() {
+		t.Errorf("expected merged histogram to have %d values from %d to %d, got %d values from %d to %d",
+			all.Count(), all.Min(), all.Max(), merged.Count(), merged.Min(), merged.Max())
+	}
+
+	for _, q := range []float64{1, 25, 50, 90, 99, 99.9, 100} {
+		if p, expected := merged.Percentile(q), all.Percentile(q); p != expected {
+			t.Errorf("expected merged p%v to be %d, got %d", q, expected, p)
+		}
+	}
+
+	if len(merged.Buckets()) != len(all.Buckets()) {
+		t.Errorf("expected %d buckets after merging, got %d", len(all.Buckets()), len(merged.Buckets()))
+	}
+}
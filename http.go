@@ -0,0 +1,242 @@
+package echo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// HTTP transport request path and headers.
+const (
+	httpRespondPath   = "/respond"
+	httpTimeoutHeader = "X-Echo-Timeout"
+)
+
+// HTTPTransport posts messages encoded as JSON to the server over HTTP/1.1
+// with keep-alive connections. The per-request timeout is sent in nanoseconds
+// in the X-Echo-Timeout header and errors are replied as a JSON object with
+// the gRPC status code and message.
+type HTTPTransport struct{}
+
+// String returns the name of the transport.
+func (HTTPTransport) String() string {
+	return "http"
+}
+
+// Serve requests on the listener until the context is canceled.
+func (t HTTPTransport) Serve(ctx context.Context, sock net.Listener, s *Server) error {
+	if err := checkInsecure(t, s.creds != nil); err != nil {
+		return err
+	}
+
+	if err := checkAuth(t, len(s.tokens) > 0); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(httpRespondPath, func(w http.ResponseWriter, r *http.Request) {
+		serveHTTP(w, r, s)
+	})
+	srv := &http.Server{Handler: mux}
+
+	// Listen for requests in its own go routine
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(sock)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		s.draining()
+	}
+
+	// Stop the server gracefully, waiting up to the drain timeout for
+	// in-flight requests to complete before closing all connections.
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.drain)
+	defer cancel()
+
+	if err := srv.Shutdown(drainCtx); err != nil {
+		warn("requests did not drain after %s, forcing stop", s.drain)
+		srv.Close()
+	}
+
+	if err := <-errc; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Dial returns a connection that posts requests to the server's address.
+func (t HTTPTransport) Dial(c *Client, timeout time.Duration) (Conn, error) {
+	if err := checkInsecure(t, c.creds != nil); err != nil {
+		return nil, err
+	}
+
+	if err := checkAuth(t, c.token != ""); err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: timeout}).DialContext,
+		MaxIdleConnsPerHost: DefaultOutstanding,
+	}
+
+	if c.dialer != nil {
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return c.dialer(addr, timeout)
+		}
+	}
+
+	conn := &httpConn{
+		url:       fmt.Sprintf("http://%s%s", c.addr, httpRespondPath),
+		client:    &http.Client{Transport: transport},
+		transport: transport,
+	}
+	return conn, nil
+}
+
+// httpError is the body of the reply when the request fails.
+type httpError struct {
+	Code  codes.Code `json:"code"`
+	Error string     `json:"error"`
+}
+
+// serveHTTP decodes the request, handles it with the server, and encodes the
+// reply or error as JSON.
+func serveHTTP(w http.ResponseWriter, r *http.Request, s *Server) {
+	if r.Method != http.MethodPost {
+		writeHTTP(w, nil, gstatus.Error(codes.Unimplemented, "messages must be posted"))
+		return
+	}
+
+	in := new(pb.BasicMessage)
+	if err := json.NewDecoder(r.Body).Decode(in); err != nil {
+		writeHTTP(w, nil, gstatus.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	var timeout time.Duration
+	if header := r.Header.Get(httpTimeoutHeader); header != "" {
+		nsecs, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			writeHTTP(w, nil, gstatus.Errorf(codes.InvalidArgument, "could not parse timeout %q", header))
+			return
+		}
+		timeout = time.Duration(nsecs)
+	}
+
+	reply, err := respondWithin(s, in, timeout)
+	writeHTTP(w, reply, err)
+}
+
+// Helper to write the reply or the error as JSON.
+func writeHTTP(w http.ResponseWriter, reply *pb.BasicMessage, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		st := gstatus.Convert(err)
+		w.WriteHeader(httpStatus(st.Code()))
+		json.NewEncoder(w).Encode(httpError{Code: st.Code(), Error: st.Message()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(reply)
+}
+
+// Helper to map gRPC status codes to HTTP status codes.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// httpConn posts requests to the server, the http client pools connections so
+// it is safe to use from multiple go routines.
+type httpConn struct {
+	url       string
+	client    *http.Client
+	transport *http.Transport
+}
+
+func (c *httpConn) Respond(ctx context.Context, in *pb.BasicMessage) (*pb.BasicMessage, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, gstatus.Error(codes.Internal, err.Error())
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, gstatus.Error(codes.Internal, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(httpTimeoutHeader, strconv.FormatInt(int64(time.Until(deadline)), 10))
+	}
+
+	rep, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, gstatus.FromContextError(ctx.Err()).Err()
+		}
+		return nil, transportError(err)
+	}
+	defer rep.Body.Close()
+
+	if rep.StatusCode != http.StatusOK {
+		herr := new(httpError)
+		if err = json.NewDecoder(rep.Body).Decode(herr); err != nil || herr.Code == codes.OK {
+			return nil, gstatus.Errorf(codes.Unknown, "http status %s", rep.Status)
+		}
+		return nil, gstatus.Error(herr.Code, herr.Error)
+	}
+
+	reply := new(pb.BasicMessage)
+	if err = json.NewDecoder(rep.Body).Decode(reply); err != nil {
+		return nil, transportError(err)
+	}
+
+	// Drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, rep.Body)
+	return reply, nil
+}
+
+func (c *httpConn) Close() error {
+	c.transport.CloseIdleConnections()
+	return nil
+}
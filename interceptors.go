@@ -0,0 +1,262 @@
+package echo
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	gstatus "google.golang.org/grpc/status"
+)
+
+//===========================================================================
+// Interceptor Chains
+//===========================================================================
+
+// ChainUnaryServer composes the interceptors into a single interceptor. The
+// first interceptor is the outermost, so it is called first and returns last.
+func ChainUnaryServer(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return chainUnaryHandler(interceptors, info, handler)(ctx, req)
+	}
+}
+
+// Helper to wrap the handler with the interceptors in order.
+func chainUnaryHandler(interceptors []grpc.UnaryServerInterceptor, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) grpc.UnaryHandler {
+	if len(interceptors) == 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptors[0](ctx, req, info, chainUnaryHandler(interceptors[1:], info, handler))
+	}
+}
+
+// ChainStreamServer composes the interceptors into a single interceptor. The
+// first interceptor is the outermost, so it is called first and returns last.
+func ChainStreamServer(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return chainStreamHandler(interceptors, info, handler)(srv, stream)
+	}
+}
+
+// Helper to wrap the handler with the interceptors in order.
+func chainStreamHandler(interceptors []grpc.StreamServerInterceptor, info *grpc.StreamServerInfo, handler grpc.StreamHandler) grpc.StreamHandler {
+	if len(interceptors) == 0 {
+		return handler
+	}
+
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		return interceptors[0](srv, stream, info, chainStreamHandler(interceptors[1:], info, handler))
+	}
+}
+
+// ChainUnaryClient composes the interceptors into a single interceptor. The
+// first interceptor is the outermost, so it is called first and returns last.
+func ChainUnaryClient(interceptors ...grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return chainUnaryInvoker(interceptors, invoker)(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// Helper to wrap the invoker with the interceptors in order.
+func chainUnaryInvoker(interceptors []grpc.UnaryClientInterceptor, invoker grpc.UnaryInvoker) grpc.UnaryInvoker {
+	if len(interceptors) == 0 {
+		return invoker
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return interceptors[0](ctx, method, req, reply, cc, chainUnaryInvoker(interceptors[1:], invoker), opts...)
+	}
+}
+
+// ChainStreamClient composes the interceptors into a single interceptor. The
+// first interceptor is the outermost, so it is called first and returns last.
+func ChainStreamClient(interceptors ...grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return chainStreamer(interceptors, streamer)(ctx, desc, cc, method, opts...)
+	}
+}
+
+// Helper to wrap the streamer with the interceptors in order.
+func chainStreamer(interceptors []grpc.StreamClientInterceptor, streamer grpc.Streamer) grpc.Streamer {
+	if len(interceptors) == 0 {
+		return streamer
+	}
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return interceptors[0](ctx, desc, cc, method, chainStreamer(interceptors[1:], streamer), opts...)
+	}
+}
+
+//===========================================================================
+// Access Logging Interceptors
+//===========================================================================
+
+// UnaryLogger logs the peer, method, status code and duration of every unary
+// request handled by the server.
+func UnaryLogger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	reply, err := handler(ctx, req)
+	logAccess(peerAddr(ctx), info.FullMethod, time.Since(start), err)
+	return reply, err
+}
+
+// StreamLogger logs the peer, method, status code and duration of every
+// stream handled by the server.
+func StreamLogger(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	logAccess(peerAddr(stream.Context()), info.FullMethod, time.Since(start), err)
+	return err
+}
+
+// UnaryClientLogger logs the method, status code and duration of every unary
+// request sent by the client.
+func UnaryClientLogger(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	logAccess(cc.Target(), method, time.Since(start), err)
+	return err
+}
+
+// StreamClientLogger logs the method, status code and time to open every
+// stream opened by the client.
+func StreamClientLogger(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	logAccess(cc.Target(), method, time.Since(start), err)
+	return stream, err
+}
+
+// Helper to log an access; interceptors are installed explicitly so the
+// access log is written at the status level.
+func logAccess(addr, method string, elapsed time.Duration, err error) {
+	if err != nil {
+		status("%s %s %s in %s: %s", addr, method, statusCode(err), elapsed, err)
+		return
+	}
+	status("%s %s %s in %s", addr, method, codes.OK, elapsed)
+}
+
+// Helper to get the address of the peer of the request.
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+//===========================================================================
+// Panic Recovery Interceptors
+//===========================================================================
+
+// UnaryRecovery recovers from panics in the handler, logging the stack and
+// returning an internal error to the client rather than crashing the server.
+func UnaryRecovery(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (reply interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// StreamRecovery recovers from panics in the handler, logging the stack and
+// returning an internal error to the client rather than crashing the server.
+func StreamRecovery(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(srv, stream)
+}
+
+// Helper to log the recovered panic and convert it into an internal error.
+func recovered(method string, r interface{}) error {
+	stack := make([]byte, 64*1024)
+	stack = stack[:runtime.Stack(stack, false)]
+	warn("recovered from panic in %s: %v", method, r)
+	debug("%s", stack)
+	return gstatus.Error(codes.Internal, fmt.Sprintf("panic in %s: %v", method, r))
+}
+
+//===========================================================================
+// Metrics Interceptors
+//===========================================================================
+
+// UnaryMetrics records the requests handled by the server in the metrics. The
+// Hello service records its own metrics, so this interceptor is intended for
+// other services that are served alongside it or embed the metrics.
+func UnaryMetrics(m *Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (reply interface{}, err error) {
+		start := time.Now()
+		m.Begin(info.FullMethod)
+		defer func() { m.Finish(time.Since(start), err) }()
+
+		client := peerAddr(ctx)
+		m.Increment(client)
+		m.Received(client, messageSize(req))
+
+		if reply, err = handler(ctx, req); err != nil {
+			return nil, err
+		}
+
+		m.Sent(client, messageSize(reply))
+		m.Complete()
+		return reply, nil
+	}
+}
+
+// StreamMetrics records the streams handled by the server in the metrics,
+// counting every message received and sent on the stream. As with
+// UnaryMetrics, it is intended for services other than the Hello service.
+func StreamMetrics(m *Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		m.Begin(info.FullMethod)
+		defer func() { m.Finish(time.Since(start), err) }()
+
+		return handler(srv, &metricsStream{ServerStream: stream, metrics: m, client: peerAddr(stream.Context())})
+	}
+}
+
+// metricsStream records the messages received and sent on a server stream.
+type metricsStream struct {
+	grpc.ServerStream
+	metrics *Metrics
+	client  string
+}
+
+func (s *metricsStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	s.metrics.Increment(s.client)
+	s.metrics.Received(s.client, messageSize(m))
+	return nil
+}
+
+func (s *metricsStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+
+	s.metrics.Sent(s.client, messageSize(m))
+	s.metrics.Complete()
+	return nil
+}
+
+// Helper to get the size of a protocol buffer message, 0 if it is not one.
+func messageSize(m interface{}) int {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package echo
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// recorder records the names of the interceptors in the order they are called.
+type recorder struct {
+	sync.Mutex
+	calls []string
+}
+
+func (r *recorder) record(name string) {
+	r.Lock()
+	defer r.Unlock()
+	r.calls = append(r.calls, name)
+}
+
+func (r *recorder) unary(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		r.record(name)
+		return handler(ctx, req)
+	}
+}
+
+func (r *recorder) stream(name string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		r.record(name)
+		return handler(srv, stream)
+	}
+}
+
+func (r *recorder) unaryClient(name string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		r.record(name)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func (r *recorder) streamClient(name string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		r.record(name)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func TestChainUnaryServer(t *testing.T) {
+	r := new(recorder)
+	chain := ChainUnaryServer(r.unary("first"), r.unary("second"), r.unary("third"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/msg.Hello/Respond"}
+
+	reply, err := chain(context.Background(), "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		r.record("handler")
+		return req, nil
+	})
+
+	if err != nil || reply != "request" {
+		t.Fatalf("expected the handler reply, got %v (%v)", reply, err)
+	}
+
+	if expected := []string{"first", "second", "third", "handler"}; !reflect.DeepEqual(r.calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, r.calls)
+	}
+
+	// An empty chain calls the handler directly
+	if reply, err = ChainUnaryServer()(context.Background(), "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}); err != nil || reply != "request" {
+		t.Errorf("expected the handler reply from an empty chain, got %v (%v)", reply, err)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/msg.Hello/Respond"}
+	_, err := UnaryRecovery(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("oops")
+	})
+
+	if gstatus.Code(err) != codes.Internal {
+		t.Errorf("expected panic to be recovered as an internal error, got %v", err)
+	}
+
+	sinfo := &grpc.StreamServerInfo{FullMethod: "/msg.Hello/Echo"}
+	err = StreamRecovery(nil, nil, sinfo, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("oops")
+	})
+
+	if gstatus.Code(err) != codes.Internal {
+		t.Errorf("expected stream panic to be recovered as an internal error, got %v", err)
+	}
+}
+
+func TestUnaryMetrics(t *testing.T) {
+	m := new(Metrics)
+	m.Init()
+
+	interceptor := UnaryMetrics(m)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	req := &pb.BasicMessage{Sender: "test", Message: "hello"}
+
+	for i := 0; i < 3; i++ {
+		interceptor(context.Background(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return req, nil
+		})
+	}
+
+	interceptor(context.Background(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+
+	if n := m.Accesses(); n != 4 {
+		t.Errorf("expected 4 accesses, got %d", n)
+	}
+
+	if n := m.Errors(); n != 1 {
+		t.Errorf("expected 1 error, got %d", n)
+	}
+
+	if n := m.InFlight(); n != 0 {
+		t.Errorf("expected no requests in flight, got %d", n)
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	r := new(recorder)
+	s, _ := NewServer("", "test")
+	s.SetReplyStrategy(EchoReply)
+	s.SetUnaryInterceptors(UnaryRecovery, r.unary("server"))
+	s.SetStreamInterceptors(StreamRecovery, r.stream("server stream"))
+	addr, stop := runServer(t, s)
+	defer stop()
+
+	c, _ := NewClient(addr, "test")
+	c.SetUnaryInterceptors(r.unaryClient("client"))
+	c.SetStreamInterceptors(r.streamClient("client stream"))
+	if err := c.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer c.Close()
+
+	if err := c.Send("hello"); err != nil {
+		t.Fatalf("could not send message: %s", err)
+	}
+
+	if err := c.Stream("hello", 2); err != nil {
+		t.Fatalf("could not stream messages: %s", err)
+	}
+
+	// Admin rpcs are also intercepted
+	if _, err := c.Status(); err != nil {
+		t.Fatalf("could not get status: %s", err)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	expected := []string{"client", "server", "client stream", "server stream", "client", "server"}
+	if !reflect.DeepEqual(r.calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, r.calls)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"os"
 	"sync"
 	"time"
+
+	"github.com/bbengfort/x/stats"
+	"google.golang.org/grpc/codes"
 )
 
 //===========================================================================
@@ -17,21 +20,83 @@ import (
 // statistics perform online computations of the distribution of values.
 type Metrics struct {
 	sync.RWMutex
-	started  time.Time         // The time of the first client message
-	finished time.Time         // The time of the last client message
-	accesses map[string]uint64 // The number of messages per-client recv by the server
+	started  time.Time             // The time of the first client message
+	finished time.Time             // The time of the last client message
+	accesses map[string]uint64     // The number of messages per-client recv by the server
+	expired  uint64                // The number of requests whose deadline passed before handling
+	rejected uint64                // The number of requests rejected as unauthenticated
+	inflight int64                 // The number of requests currently being handled
+	requests map[string]uint64     // The number of requests handled per rpc method
+	errors   map[codes.Code]uint64 // The number of failed requests per status code
+	handler  []uint64              // The number of requests per handler duration bucket
+	handled  time.Duration         // The total time spent handling requests
+	faults   map[string]uint64     // The number of faults injected per kind
+
+	handling *stats.Statistics            // The distribution of request handler durations
+	arrivals *stats.Statistics            // The distribution of time between received messages
+	arrived  time.Time                    // The time the last message was received
+	bytesIn  map[string]*stats.Statistics // The distribution of request sizes per-client
+	bytesOut map[string]*stats.Statistics // The distribution of reply sizes per-client
+	series   *Series                      // The throughput and handler time over intervals
+}
+
+// HandlerBuckets are the upper bounds of the handler duration histogram.
+var HandlerBuckets = []time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
 }
 
 // Init the metrics
 func (m *Metrics) Init() {
 	m.accesses = make(map[string]uint64)
+	m.requests = make(map[string]uint64)
+	m.errors = make(map[codes.Code]uint64)
+	m.faults = make(map[string]uint64)
+	m.handler = make([]uint64, len(HandlerBuckets)+1)
+	m.handling = new(stats.Statistics)
+	m.arrivals = new(stats.Statistics)
+	m.bytesIn = make(map[string]*stats.Statistics)
+	m.bytesOut = make(map[string]*stats.Statistics)
+}
+
+// Track the throughput and handler time of requests completed in windows of
+// the interval beginning at the start time.
+func (m *Metrics) Track(interval time.Duration, start time.Time) {
+	m.Lock()
+	defer m.Unlock()
+	m.series = NewSeries(interval, start)
+}
+
+// Series returns the time series of the metrics, nil if it is not tracked.
+func (m *Metrics) Series() *Series {
+	m.RLock()
+	defer m.RUnlock()
+	return m.series
 }
 
 // Accesses returns the total number of accesses to the replica.
 func (m *Metrics) Accesses() uint64 {
 	m.RLock()
 	defer m.RUnlock()
+	return m.totalAccesses()
+}
 
+// totalAccesses must be called with the lock held since it is not reentrant.
+func (m *Metrics) totalAccesses() uint64 {
 	var total uint64
 	for _, count := range m.accesses {
 		total += count
@@ -39,18 +104,139 @@ func (m *Metrics) Accesses() uint64 {
 	return total
 }
 
-// Increment the access metrics and set the started time.
+// Increment the access metrics and set the started time, recording the time
+// since the previous message was received from any client.
 func (m *Metrics) Increment(client string) {
 	m.Lock()
 	defer m.Unlock()
 
+	now := time.Now()
 	if m.started.IsZero() {
-		m.started = time.Now()
+		m.started = now
+	}
+
+	if !m.arrived.IsZero() {
+		m.arrivals.Update(float64(now.Sub(m.arrived)))
 	}
+	m.arrived = now
 
 	m.accesses[client]++
 }
 
+// Received records the size in bytes of a message received from the client.
+func (m *Metrics) Received(client string, nbytes int) {
+	m.Lock()
+	defer m.Unlock()
+	distribution(m.bytesIn, client).Update(float64(nbytes))
+}
+
+// Sent records the size in bytes of a reply sent to the client.
+func (m *Metrics) Sent(client string, nbytes int) {
+	m.Lock()
+	defer m.Unlock()
+	distribution(m.bytesOut, client).Update(float64(nbytes))
+}
+
+// Expire records a request that was not handled because its deadline passed.
+func (m *Metrics) Expire() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.expired++
+}
+
+// Expired returns the number of requests that were dropped past their deadline.
+func (m *Metrics) Expired() uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.expired
+}
+
+// Reject records a request that was rejected because it was not authenticated.
+func (m *Metrics) Reject() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.rejected++
+}
+
+// Rejected returns the number of requests rejected as unauthenticated.
+func (m *Metrics) Rejected() uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.rejected
+}
+
+// Begin handling a request to the rpc method.
+func (m *Metrics) Begin(method string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.inflight++
+	m.requests[method]++
+}
+
+// Finish handling a request that took the elapsed time, counting the status
+// code of the error if the request failed.
+func (m *Metrics) Finish(elapsed time.Duration, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.inflight--
+	m.handled += elapsed
+	m.handling.Update(float64(elapsed))
+
+	// The last bucket counts durations above the largest bound
+	i := 0
+	for i < len(HandlerBuckets) && elapsed > HandlerBuckets[i] {
+		i++
+	}
+	m.handler[i]++
+
+	if err != nil {
+		m.errors[statusCode(err)]++
+	} else if m.series != nil {
+		m.series.Record(time.Now(), 1, elapsed)
+	}
+}
+
+// Inject records a fault of the kind injected into the handling of a request.
+func (m *Metrics) Inject(fault string) {
+	m.Lock()
+	defer m.Unlock()
+	m.faults[fault]++
+}
+
+// Injected returns the number of faults of the kind that have been injected.
+func (m *Metrics) Injected(fault string) uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.faults[fault]
+}
+
+// InFlight returns the number of requests currently being handled.
+func (m *Metrics) InFlight() int64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.inflight
+}
+
+// Errors returns the total number of requests that failed.
+func (m *Metrics) Errors() uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.totalErrors()
+}
+
+// totalErrors must be called with the lock held.
+func (m *Metrics) totalErrors() uint64 {
+	var total uint64
+	for _, count := range m.errors {
+		total += count
+	}
+	return total
+}
+
 // Complete an access and set the finished time.
 func (m *Metrics) Complete() {
 	m.Lock()
@@ -63,16 +249,29 @@ func (m *Metrics) Complete() {
 func (m *Metrics) Duration() time.Duration {
 	m.RLock()
 	defer m.RUnlock()
+	return m.duration()
+}
+
+// duration must be called with the lock held. The duration is zero if no
+// request has completed, e.g. if every request failed.
+func (m *Metrics) duration() time.Duration {
+	if m.finished.Before(m.started) {
+		return 0
+	}
 	return m.finished.Sub(m.started)
 }
 
 // Throughput computes the number of messages per second.
-func (m *Metrics) Throughput() (throughput float64) {
+func (m *Metrics) Throughput() float64 {
 	m.RLock()
 	defer m.RUnlock()
+	return m.throughput()
+}
 
-	duration := m.Duration()
-	accesses := m.Accesses()
+// throughput must be called with the lock held.
+func (m *Metrics) throughput() (throughput float64) {
+	duration := m.duration()
+	accesses := m.totalAccesses()
 
 	if accesses > 0 && duration > 0 {
 		throughput = float64(accesses) / duration.Seconds()
@@ -85,20 +284,18 @@ func (m *Metrics) Throughput() (throughput float64) {
 func (m *Metrics) NClients() uint64 {
 	m.RLock()
 	defer m.RUnlock()
-
-	var n uint64
-	for _ = range m.accesses {
-		n++
-	}
-
-	return n
+	return uint64(len(m.accesses))
 }
 
 // ClientMean returns the average number of accesses per client.
 func (m *Metrics) ClientMean() float64 {
 	m.RLock()
 	defer m.RUnlock()
+	return m.clientMean()
+}
 
+// clientMean must be called with the lock held.
+func (m *Metrics) clientMean() float64 {
 	var n uint64
 	var s uint64
 	for _, count := range m.accesses {
@@ -119,11 +316,31 @@ func (m *Metrics) Serialize(extra map[string]interface{}) map[string]interface{}
 	defer m.RUnlock()
 
 	data := make(map[string]interface{})
-	data["clients"] = m.NClients()
-	data["accesses"] = m.Accesses()
-	data["mean"] = m.ClientMean()
-	data["duration"] = m.Duration().String()
-	data["throughput"] = m.Throughput()
+	data["clients"] = uint64(len(m.accesses))
+	data["accesses"] = m.totalAccesses()
+	data["mean"] = m.clientMean()
+	data["duration"] = m.duration().String()
+	data["throughput"] = m.throughput()
+	data["expired"] = m.expired
+	data["rejected"] = m.rejected
+	data["errors"] = m.totalErrors()
+	data["faults"] = copyCounts(m.faults)
+	data["handler (nsec)"] = m.handling.Serialize()
+	data["interarrival (nsec)"] = m.arrivals.Serialize()
+
+	bytes := make(map[string]interface{}, len(m.accesses))
+	for client := range m.accesses {
+		bytes[client] = map[string]interface{}{
+			"in":  serializeDistribution(m.bytesIn[client]),
+			"out": serializeDistribution(m.bytesOut[client]),
+		}
+	}
+	data["bytes"] = bytes
+
+	if m.series != nil {
+		data["interval (nsec)"] = m.series.Interval().Nanoseconds()
+		data["series"] = m.series.Serialize()
+	}
 
 	for key, val := range extra {
 		data[key] = val
@@ -139,7 +356,7 @@ func (m *Metrics) String() string {
 
 	return fmt.Sprintf(
 		"%d accesses by %d clients in %s -- %0.4f accesses/second",
-		m.Accesses(), m.NClients(), m.Duration(), m.Throughput(),
+		m.totalAccesses(), len(m.accesses), m.duration(), m.throughput(),
 	)
 }
 
@@ -149,11 +366,57 @@ func (m *Metrics) Append(o *Metrics) {
 	o.RLock()
 	defer m.Unlock()
 	defer o.RUnlock()
+	m.merge(o)
+}
 
+// merge must be called with the lock held and the read lock of the other held.
+func (m *Metrics) merge(o *Metrics) {
 	// Increment the counts
 	for client, count := range o.accesses {
 		m.accesses[client] += count
 	}
+	m.expired += o.expired
+	m.rejected += o.rejected
+
+	for method, count := range o.requests {
+		m.requests[method] += count
+	}
+
+	for code, count := range o.errors {
+		m.errors[code] += count
+	}
+
+	for fault, count := range o.faults {
+		m.faults[fault] += count
+	}
+
+	for i, count := range o.handler {
+		m.handler[i] += count
+	}
+	m.handled += o.handled
+	m.handling.Append(o.handling)
+
+	// Inter-arrival times are only measured within each set of metrics, the
+	// gap between the last message of one and the first of the other is not.
+	m.arrivals.Append(o.arrivals)
+	if o.arrived.After(m.arrived) {
+		m.arrived = o.arrived
+	}
+
+	for client, dist := range o.bytesIn {
+		distribution(m.bytesIn, client).Append(dist)
+	}
+
+	for client, dist := range o.bytesOut {
+		distribution(m.bytesOut, client).Append(dist)
+	}
+
+	if o.series != nil {
+		if m.series == nil {
+			m.series = NewSeries(o.series.Interval(), time.Time{})
+		}
+		m.series.Merge(o.series)
+	}
 
 	// If the other started time is earlier, set it as started
 	if !o.started.IsZero() && (m.started.IsZero() || o.started.Before(m.started)) {
@@ -166,6 +429,90 @@ func (m *Metrics) Append(o *Metrics) {
 	}
 }
 
+// Snapshot returns a copy of the metrics at the current time that is not
+// modified by subsequent requests.
+func (m *Metrics) Snapshot() *Metrics {
+	snap := new(Metrics)
+	snap.Init()
+
+	m.RLock()
+	defer m.RUnlock()
+
+	snap.merge(m)
+	snap.inflight = m.inflight
+	return snap
+}
+
+// Reset the metrics to begin a fresh measurement, returning the metrics that
+// were collected until the reset. Requests in flight remain in flight and are
+// counted in the fresh metrics when they finish. If a time series is tracked,
+// the fresh series begins at the time of the reset.
+func (m *Metrics) Reset() *Metrics {
+	m.Lock()
+	defer m.Unlock()
+
+	old := &Metrics{
+		started:  m.started,
+		finished: m.finished,
+		accesses: m.accesses,
+		expired:  m.expired,
+		rejected: m.rejected,
+		inflight: m.inflight,
+		requests: m.requests,
+		errors:   m.errors,
+		handler:  m.handler,
+		handled:  m.handled,
+		faults:   m.faults,
+		handling: m.handling,
+		arrivals: m.arrivals,
+		arrived:  m.arrived,
+		bytesIn:  m.bytesIn,
+		bytesOut: m.bytesOut,
+		series:   m.series,
+	}
+
+	m.Init()
+	m.started = time.Time{}
+	m.finished = time.Time{}
+	m.arrived = time.Time{}
+	m.expired = 0
+	m.rejected = 0
+	m.handled = 0
+
+	if old.series != nil {
+		m.series = NewSeries(old.series.Interval(), time.Now())
+	}
+
+	return old
+}
+
+// Helper to get the distribution of the client, creating it if necessary.
+func distribution(dists map[string]*stats.Statistics, client string) *stats.Statistics {
+	dist, ok := dists[client]
+	if !ok {
+		dist = new(stats.Statistics)
+		dists[client] = dist
+	}
+	return dist
+}
+
+// Helper to copy counts so that they can be serialized without the lock.
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	c := make(map[string]uint64, len(counts))
+	for key, count := range counts {
+		c[key] = count
+	}
+	return c
+}
+
+// Helper to serialize a distribution that may not have been created.
+func serializeDistribution(dist *stats.Statistics) map[string]interface{} {
+	if dist == nil {
+		dist = new(stats.Statistics)
+	}
+	return dist.Serialize()
+}
+
 // Write the metrics to the path, appending the JSON as a line to the file.
 func (m *Metrics) Write(path string, extra map[string]interface{}) error {
 	// Don't do anything if no path is given
@@ -0,0 +1,48 @@
+package echo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Modes describe which of the Hello service RPCs is used to send messages to
+// the server during a benchmark so that their per-message cost can be compared.
+const (
+	Unary Mode = iota
+	ServerStream
+	ClientStream
+	BidiStream
+)
+
+// DefaultBatch is the number of messages sent or received per RPC by the
+// server and client streaming benchmark modes.
+const DefaultBatch = 10
+
+var modeStrings = [...]string{"unary", "server-stream", "client-stream", "bidi"}
+
+// Mode is the type of RPC used to send messages in a benchmark.
+type Mode uint8
+
+// ParseMode returns the mode from its string representation.
+func ParseMode(s string) (Mode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range modeStrings {
+		if s == name {
+			return Mode(i), nil
+		}
+	}
+	return Unary, fmt.Errorf("unknown mode %q, use one of %s", s, strings.Join(modeStrings[:], ", "))
+}
+
+// String returns a human readable representation of the mode.
+func (m Mode) String() string {
+	if int(m) < len(modeStrings) {
+		return modeStrings[m]
+	}
+	return fmt.Sprintf("mode(%d)", m)
+}
+
+// batched returns true if each rpc of the mode carries a batch of messages.
+func (m Mode) batched() bool {
+	return m == ServerStream || m == ClientStream
+}
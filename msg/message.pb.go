@@ -9,6 +9,11 @@ It is generated from these files:
 
 It has these top-level messages:
 	BasicMessage
+	StatusRequest
+	StatusReply
+	ResetRequest
+	ResetReply
+	Faults
 */
 package msg
 
@@ -33,8 +38,11 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type BasicMessage struct {
-	Sender  string `protobuf:"bytes,1,opt,name=sender" json:"sender,omitempty"`
-	Message string `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+	Sender    string `protobuf:"bytes,1,opt,name=sender" json:"sender,omitempty"`
+	Message   string `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+	Repeat    uint32 `protobuf:"varint,3,opt,name=repeat" json:"repeat,omitempty"`
+	Payload   []byte `protobuf:"bytes,4,opt,name=payload" json:"payload,omitempty"`
+	ReplySize uint32 `protobuf:"varint,5,opt,name=reply_size,json=replySize" json:"reply_size,omitempty"`
 }
 
 func (m *BasicMessage) Reset()                    { *m = BasicMessage{} }
@@ -56,8 +64,250 @@ func (m *BasicMessage) GetMessage() string {
 	return ""
 }
 
+func (m *BasicMessage) GetRepeat() uint32 {
+	if m != nil {
+		return m.Repeat
+	}
+	return 0
+}
+
+func (m *BasicMessage) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *BasicMessage) GetReplySize() uint32 {
+	if m != nil {
+		return m.ReplySize
+	}
+	return 0
+}
+
+type StatusRequest struct {
+}
+
+func (m *StatusRequest) Reset()                    { *m = StatusRequest{} }
+func (m *StatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*StatusRequest) ProtoMessage()               {}
+func (*StatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type StatusReply struct {
+	Name       string  `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Version    string  `protobuf:"bytes,2,opt,name=version" json:"version,omitempty"`
+	Transport  string  `protobuf:"bytes,3,opt,name=transport" json:"transport,omitempty"`
+	LogLevel   string  `protobuf:"bytes,4,opt,name=log_level,json=logLevel" json:"log_level,omitempty"`
+	Uptime     int64   `protobuf:"varint,5,opt,name=uptime" json:"uptime,omitempty"`
+	Accesses   uint64  `protobuf:"varint,6,opt,name=accesses" json:"accesses,omitempty"`
+	Clients    uint64  `protobuf:"varint,7,opt,name=clients" json:"clients,omitempty"`
+	Throughput float64 `protobuf:"fixed64,8,opt,name=throughput" json:"throughput,omitempty"`
+	InFlight   int64   `protobuf:"varint,9,opt,name=in_flight,json=inFlight" json:"in_flight,omitempty"`
+	Errors     uint64  `protobuf:"varint,10,opt,name=errors" json:"errors,omitempty"`
+	Expired    uint64  `protobuf:"varint,11,opt,name=expired" json:"expired,omitempty"`
+	Faults     string  `protobuf:"bytes,12,opt,name=faults" json:"faults,omitempty"`
+}
+
+func (m *StatusReply) Reset()                    { *m = StatusReply{} }
+func (m *StatusReply) String() string            { return proto.CompactTextString(m) }
+func (*StatusReply) ProtoMessage()               {}
+func (*StatusReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *StatusReply) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *StatusReply) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *StatusReply) GetTransport() string {
+	if m != nil {
+		return m.Transport
+	}
+	return ""
+}
+
+func (m *StatusReply) GetLogLevel() string {
+	if m != nil {
+		return m.LogLevel
+	}
+	return ""
+}
+
+func (m *StatusReply) GetUptime() int64 {
+	if m != nil {
+		return m.Uptime
+	}
+	return 0
+}
+
+func (m *StatusReply) GetAccesses() uint64 {
+	if m != nil {
+		return m.Accesses
+	}
+	return 0
+}
+
+func (m *StatusReply) GetClients() uint64 {
+	if m != nil {
+		return m.Clients
+	}
+	return 0
+}
+
+func (m *StatusReply) GetThroughput() float64 {
+	if m != nil {
+		return m.Throughput
+	}
+	return 0
+}
+
+func (m *StatusReply) GetInFlight() int64 {
+	if m != nil {
+		return m.InFlight
+	}
+	return 0
+}
+
+func (m *StatusReply) GetErrors() uint64 {
+	if m != nil {
+		return m.Errors
+	}
+	return 0
+}
+
+func (m *StatusReply) GetExpired() uint64 {
+	if m != nil {
+		return m.Expired
+	}
+	return 0
+}
+
+func (m *StatusReply) GetFaults() string {
+	if m != nil {
+		return m.Faults
+	}
+	return ""
+}
+
+type ResetRequest struct {
+	Label string `protobuf:"bytes,1,opt,name=label" json:"label,omitempty"`
+}
+
+func (m *ResetRequest) Reset()                    { *m = ResetRequest{} }
+func (m *ResetRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResetRequest) ProtoMessage()               {}
+func (*ResetRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *ResetRequest) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+type ResetReply struct {
+	Label      string  `protobuf:"bytes,1,opt,name=label" json:"label,omitempty"`
+	Accesses   uint64  `protobuf:"varint,2,opt,name=accesses" json:"accesses,omitempty"`
+	Clients    uint64  `protobuf:"varint,3,opt,name=clients" json:"clients,omitempty"`
+	Duration   int64   `protobuf:"varint,4,opt,name=duration" json:"duration,omitempty"`
+	Throughput float64 `protobuf:"fixed64,5,opt,name=throughput" json:"throughput,omitempty"`
+}
+
+func (m *ResetReply) Reset()                    { *m = ResetReply{} }
+func (m *ResetReply) String() string            { return proto.CompactTextString(m) }
+func (*ResetReply) ProtoMessage()               {}
+func (*ResetReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *ResetReply) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *ResetReply) GetAccesses() uint64 {
+	if m != nil {
+		return m.Accesses
+	}
+	return 0
+}
+
+func (m *ResetReply) GetClients() uint64 {
+	if m != nil {
+		return m.Clients
+	}
+	return 0
+}
+
+func (m *ResetReply) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *ResetReply) GetThroughput() float64 {
+	if m != nil {
+		return m.Throughput
+	}
+	return 0
+}
+
+type Faults struct {
+	Delay     string  `protobuf:"bytes,1,opt,name=delay" json:"delay,omitempty"`
+	ErrorRate float64 `protobuf:"fixed64,2,opt,name=error_rate,json=errorRate" json:"error_rate,omitempty"`
+	ErrorCode uint32  `protobuf:"varint,3,opt,name=error_code,json=errorCode" json:"error_code,omitempty"`
+	HangRate  float64 `protobuf:"fixed64,4,opt,name=hang_rate,json=hangRate" json:"hang_rate,omitempty"`
+}
+
+func (m *Faults) Reset()                    { *m = Faults{} }
+func (m *Faults) String() string            { return proto.CompactTextString(m) }
+func (*Faults) ProtoMessage()               {}
+func (*Faults) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *Faults) GetDelay() string {
+	if m != nil {
+		return m.Delay
+	}
+	return ""
+}
+
+func (m *Faults) GetErrorRate() float64 {
+	if m != nil {
+		return m.ErrorRate
+	}
+	return 0
+}
+
+func (m *Faults) GetErrorCode() uint32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *Faults) GetHangRate() float64 {
+	if m != nil {
+		return m.HangRate
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*BasicMessage)(nil), "msg.BasicMessage")
+	proto.RegisterType((*StatusRequest)(nil), "msg.StatusRequest")
+	proto.RegisterType((*StatusReply)(nil), "msg.StatusReply")
+	proto.RegisterType((*ResetRequest)(nil), "msg.ResetRequest")
+	proto.RegisterType((*ResetReply)(nil), "msg.ResetReply")
+	proto.RegisterType((*Faults)(nil), "msg.Faults")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -72,6 +322,9 @@ const _ = grpc.SupportPackageIsVersion4
 
 type HelloClient interface {
 	Respond(ctx context.Context, in *BasicMessage, opts ...grpc.CallOption) (*BasicMessage, error)
+	Stream(ctx context.Context, in *BasicMessage, opts ...grpc.CallOption) (Hello_StreamClient, error)
+	Collect(ctx context.Context, opts ...grpc.CallOption) (Hello_CollectClient, error)
+	Echo(ctx context.Context, opts ...grpc.CallOption) (Hello_EchoClient, error)
 }
 
 type helloClient struct {
@@ -91,10 +344,110 @@ func (c *helloClient) Respond(ctx context.Context, in *BasicMessage, opts ...grp
 	return out, nil
 }
 
+func (c *helloClient) Stream(ctx context.Context, in *BasicMessage, opts ...grpc.CallOption) (Hello_StreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Hello_serviceDesc.Streams[0], c.cc, "/msg.Hello/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &helloStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Hello_StreamClient interface {
+	Recv() (*BasicMessage, error)
+	grpc.ClientStream
+}
+
+type helloStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *helloStreamClient) Recv() (*BasicMessage, error) {
+	m := new(BasicMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *helloClient) Collect(ctx context.Context, opts ...grpc.CallOption) (Hello_CollectClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Hello_serviceDesc.Streams[1], c.cc, "/msg.Hello/Collect", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &helloCollectClient{stream}
+	return x, nil
+}
+
+type Hello_CollectClient interface {
+	Send(*BasicMessage) error
+	CloseAndRecv() (*BasicMessage, error)
+	grpc.ClientStream
+}
+
+type helloCollectClient struct {
+	grpc.ClientStream
+}
+
+func (x *helloCollectClient) Send(m *BasicMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *helloCollectClient) CloseAndRecv() (*BasicMessage, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BasicMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *helloClient) Echo(ctx context.Context, opts ...grpc.CallOption) (Hello_EchoClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Hello_serviceDesc.Streams[2], c.cc, "/msg.Hello/Echo", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &helloEchoClient{stream}
+	return x, nil
+}
+
+type Hello_EchoClient interface {
+	Send(*BasicMessage) error
+	Recv() (*BasicMessage, error)
+	grpc.ClientStream
+}
+
+type helloEchoClient struct {
+	grpc.ClientStream
+}
+
+func (x *helloEchoClient) Send(m *BasicMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *helloEchoClient) Recv() (*BasicMessage, error) {
+	m := new(BasicMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for Hello service
 
 type HelloServer interface {
 	Respond(context.Context, *BasicMessage) (*BasicMessage, error)
+	Stream(*BasicMessage, Hello_StreamServer) error
+	Collect(Hello_CollectServer) error
+	Echo(Hello_EchoServer) error
 }
 
 func RegisterHelloServer(s *grpc.Server, srv HelloServer) {
@@ -119,6 +472,79 @@ func _Hello_Respond_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Hello_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BasicMessage)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HelloServer).Stream(m, &helloStreamServer{stream})
+}
+
+type Hello_StreamServer interface {
+	Send(*BasicMessage) error
+	grpc.ServerStream
+}
+
+type helloStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *helloStreamServer) Send(m *BasicMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Hello_Collect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(HelloServer).Collect(&helloCollectServer{stream})
+}
+
+type Hello_CollectServer interface {
+	SendAndClose(*BasicMessage) error
+	Recv() (*BasicMessage, error)
+	grpc.ServerStream
+}
+
+type helloCollectServer struct {
+	grpc.ServerStream
+}
+
+func (x *helloCollectServer) SendAndClose(m *BasicMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *helloCollectServer) Recv() (*BasicMessage, error) {
+	m := new(BasicMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Hello_Echo_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(HelloServer).Echo(&helloEchoServer{stream})
+}
+
+type Hello_EchoServer interface {
+	Send(*BasicMessage) error
+	Recv() (*BasicMessage, error)
+	grpc.ServerStream
+}
+
+type helloEchoServer struct {
+	grpc.ServerStream
+}
+
+func (x *helloEchoServer) Send(m *BasicMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *helloEchoServer) Recv() (*BasicMessage, error) {
+	m := new(BasicMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Hello_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "msg.Hello",
 	HandlerType: (*HelloServer)(nil),
@@ -128,6 +554,153 @@ var _Hello_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Hello_Respond_Handler,
 		},
 	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _Hello_Stream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Collect",
+			Handler:       _Hello_Collect_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Echo",
+			Handler:       _Hello_Echo_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "message.proto",
+}
+
+// Client API for Admin service
+
+type AdminClient interface {
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusReply, error)
+	Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*ResetReply, error)
+	Inject(ctx context.Context, in *Faults, opts ...grpc.CallOption) (*Faults, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusReply, error) {
+	out := new(StatusReply)
+	err := grpc.Invoke(ctx, "/msg.Admin/Status", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*ResetReply, error) {
+	out := new(ResetReply)
+	err := grpc.Invoke(ctx, "/msg.Admin/Reset", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Inject(ctx context.Context, in *Faults, opts ...grpc.CallOption) (*Faults, error) {
+	out := new(Faults)
+	err := grpc.Invoke(ctx, "/msg.Admin/Inject", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Admin service
+
+type AdminServer interface {
+	Status(context.Context, *StatusRequest) (*StatusReply, error)
+	Reset(context.Context, *ResetRequest) (*ResetReply, error)
+	Inject(context.Context, *Faults) (*Faults, error)
+}
+
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&_Admin_serviceDesc, srv)
+}
+
+func _Admin_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Admin/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Reset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Reset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Admin/Reset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Reset(ctx, req.(*ResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Inject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Faults)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Inject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Admin/Inject",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Inject(ctx, req.(*Faults))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Admin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "msg.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Status",
+			Handler:    _Admin_Status_Handler,
+		},
+		{
+			MethodName: "Reset",
+			Handler:    _Admin_Reset_Handler,
+		},
+		{
+			MethodName: "Inject",
+			Handler:    _Admin_Inject_Handler,
+		},
+	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "message.proto",
 }
@@ -135,13 +708,42 @@ var _Hello_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("message.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 126 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xcd, 0x4d, 0x2d, 0x2e,
-	0x4e, 0x4c, 0x4f, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0xce, 0x2d, 0x4e, 0x57, 0x72,
-	0xe0, 0xe2, 0x71, 0x4a, 0x2c, 0xce, 0x4c, 0xf6, 0x85, 0x48, 0x09, 0x89, 0x71, 0xb1, 0x15, 0xa7,
-	0xe6, 0xa5, 0xa4, 0x16, 0x49, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0x41, 0x79, 0x42, 0x12, 0x5c,
-	0xec, 0x50, 0xdd, 0x12, 0x4c, 0x60, 0x09, 0x18, 0xd7, 0xc8, 0x8a, 0x8b, 0xd5, 0x23, 0x35, 0x27,
-	0x27, 0x5f, 0xc8, 0x90, 0x8b, 0x3d, 0x28, 0xb5, 0xb8, 0x20, 0x3f, 0x2f, 0x45, 0x48, 0x50, 0x2f,
-	0xb7, 0x38, 0x5d, 0x0f, 0xd9, 0x60, 0x29, 0x4c, 0x21, 0x25, 0x86, 0x24, 0x36, 0xb0, 0x4b, 0x8c,
-	0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0x38, 0x64, 0x60, 0x20, 0x9a, 0x00, 0x00, 0x00,
+	// 579 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xed, 0x6e, 0xd3, 0x30,
+	0x14, 0x9d, 0xd7, 0x36, 0x6d, 0xee, 0x3a, 0x0d, 0x2c, 0x84, 0xac, 0xf2, 0xa1, 0x2a, 0xda, 0x8f,
+	0xfe, 0x61, 0x1a, 0xdd, 0x13, 0xc0, 0xc4, 0x04, 0x12, 0xfc, 0xf1, 0x1e, 0xa0, 0xf2, 0x92, 0xbb,
+	0x34, 0xc8, 0x89, 0x83, 0xed, 0x4c, 0x74, 0x0f, 0x81, 0x84, 0xc4, 0x5b, 0xf1, 0x00, 0xbc, 0x0e,
+	0xf2, 0x47, 0x58, 0x3b, 0x40, 0xda, 0xbf, 0x9e, 0x73, 0x7d, 0x7a, 0x8f, 0xcf, 0xbd, 0x0e, 0x1c,
+	0xd6, 0x68, 0x8c, 0x28, 0xf1, 0xa4, 0xd5, 0xca, 0x2a, 0x3a, 0xa8, 0x4d, 0x99, 0x7d, 0x27, 0x30,
+	0x7d, 0x2b, 0x4c, 0x95, 0x7f, 0x0a, 0x35, 0xfa, 0x14, 0x12, 0x83, 0x4d, 0x81, 0x9a, 0x91, 0x39,
+	0x59, 0xa4, 0x3c, 0x22, 0xca, 0x60, 0x1c, 0xe5, 0x6c, 0xdf, 0x17, 0xc6, 0xf5, 0x9d, 0x42, 0x63,
+	0x8b, 0xc2, 0xb2, 0xc1, 0x9c, 0x2c, 0x0e, 0x79, 0x44, 0x4e, 0xd1, 0x8a, 0x8d, 0x54, 0xa2, 0x60,
+	0xc3, 0x39, 0x59, 0x4c, 0x79, 0x0f, 0xe9, 0x0b, 0x00, 0x8d, 0xad, 0xdc, 0xac, 0x4c, 0x75, 0x8b,
+	0x6c, 0xe4, 0x55, 0xa9, 0x67, 0x2e, 0xab, 0x5b, 0xcc, 0x8e, 0xe0, 0xf0, 0xd2, 0x0a, 0xdb, 0x19,
+	0x8e, 0x5f, 0x3a, 0x34, 0x36, 0xfb, 0xb9, 0x0f, 0x07, 0x3d, 0xd3, 0xca, 0x0d, 0xa5, 0x30, 0x6c,
+	0x44, 0x8d, 0xd1, 0xa1, 0xff, 0xed, 0xba, 0xdd, 0xa0, 0x36, 0x95, 0x6a, 0x7a, 0x7f, 0x11, 0xd2,
+	0xe7, 0x90, 0x5a, 0x2d, 0x1a, 0xd3, 0x2a, 0x1d, 0x2c, 0xa6, 0xfc, 0x8e, 0xa0, 0xcf, 0x20, 0x95,
+	0xaa, 0x5c, 0x49, 0xbc, 0x41, 0xe9, 0x7d, 0xa6, 0x7c, 0x22, 0x55, 0xf9, 0xd1, 0x61, 0x77, 0xb5,
+	0xae, 0xb5, 0x55, 0x1d, 0x4c, 0x0e, 0x78, 0x44, 0x74, 0x06, 0x13, 0x91, 0xe7, 0x68, 0x0c, 0x1a,
+	0x96, 0xcc, 0xc9, 0x62, 0xc8, 0xff, 0x60, 0x67, 0x24, 0x97, 0x15, 0x36, 0xd6, 0xb0, 0xb1, 0x2f,
+	0xf5, 0x90, 0xbe, 0x04, 0xb0, 0x6b, 0xad, 0xba, 0x72, 0xdd, 0x76, 0x96, 0x4d, 0xe6, 0x64, 0x41,
+	0xf8, 0x16, 0xe3, 0xac, 0x54, 0xcd, 0xea, 0x5a, 0x56, 0xe5, 0xda, 0xb2, 0xd4, 0x37, 0x9c, 0x54,
+	0xcd, 0x85, 0xc7, 0xce, 0x0a, 0x6a, 0xad, 0xb4, 0x61, 0xe0, 0xff, 0x35, 0x22, 0xd7, 0x0e, 0xbf,
+	0xb6, 0x95, 0xc6, 0x82, 0x1d, 0x84, 0x76, 0x11, 0x3a, 0xc5, 0xb5, 0xe8, 0xa4, 0x35, 0x6c, 0x1a,
+	0x26, 0x19, 0x50, 0x76, 0x0c, 0x53, 0x8e, 0x06, 0x6d, 0x4c, 0x97, 0x3e, 0x81, 0x91, 0x14, 0x57,
+	0x28, 0x63, 0x9c, 0x01, 0x64, 0x3f, 0x08, 0x40, 0x3c, 0xe6, 0x22, 0xff, 0xe7, 0xa1, 0x9d, 0x1c,
+	0xf6, 0xff, 0x9f, 0xc3, 0x60, 0x37, 0x87, 0x19, 0x4c, 0x8a, 0x4e, 0x0b, 0xeb, 0x66, 0x35, 0x0c,
+	0xd7, 0xec, 0xf1, 0xbd, 0x8c, 0x46, 0xf7, 0x33, 0xca, 0x36, 0x90, 0x5c, 0xf8, 0x6b, 0x38, 0x47,
+	0x05, 0x4a, 0xb1, 0xe9, 0x1d, 0x79, 0xe0, 0x56, 0xcb, 0x07, 0xb3, 0xd2, 0xc2, 0x86, 0x4d, 0x25,
+	0x3c, 0xf5, 0x0c, 0x17, 0x16, 0xef, 0xca, 0xb9, 0x2a, 0x30, 0xee, 0x6b, 0x28, 0x9f, 0xab, 0x02,
+	0xdd, 0x04, 0xd6, 0xa2, 0x29, 0x83, 0x78, 0xe8, 0xc5, 0x13, 0x47, 0x38, 0xed, 0xf2, 0x17, 0x81,
+	0xd1, 0x7b, 0x94, 0x52, 0xd1, 0xd7, 0x30, 0xe6, 0x68, 0x5a, 0xd5, 0x14, 0xf4, 0xf1, 0x49, 0x6d,
+	0xca, 0x93, 0xed, 0x17, 0x34, 0xfb, 0x9b, 0xca, 0xf6, 0xe8, 0x12, 0x92, 0x4b, 0xab, 0x51, 0xd4,
+	0x0f, 0x55, 0x9c, 0x12, 0x7a, 0x06, 0xe3, 0x73, 0x25, 0x25, 0xe6, 0xf6, 0xa1, 0xa2, 0x05, 0xa1,
+	0x4b, 0x18, 0xbe, 0xcb, 0xd7, 0xea, 0xe1, 0x8a, 0x53, 0xb2, 0xfc, 0x46, 0x60, 0xf4, 0xa6, 0xa8,
+	0xab, 0x86, 0x9e, 0x42, 0x12, 0x1e, 0x1a, 0xa5, 0xfe, 0xf0, 0xce, 0x3b, 0x9c, 0x3d, 0xda, 0xe1,
+	0x5a, 0xb9, 0xc9, 0xf6, 0xe8, 0x2b, 0x18, 0xf9, 0x35, 0x89, 0x0d, 0xb7, 0x37, 0x6b, 0x76, 0xb4,
+	0x4d, 0x85, 0xe3, 0xc7, 0x90, 0x7c, 0x68, 0x3e, 0xbb, 0x2b, 0x1d, 0xf8, 0x62, 0x18, 0xe6, 0x6c,
+	0x1b, 0x64, 0x7b, 0x57, 0x89, 0xff, 0x42, 0x9d, 0xfd, 0x1e, 0x00, 0xeb, 0xab, 0x17, 0x03, 0xb2,
+	0x04, 0x00, 0x00,
 }
@@ -0,0 +1,231 @@
+package echo
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Option configures a Server created with NewServer or a Client created with
+// NewClient. Most options apply to both; options that only apply to one return
+// an error from the constructor of the other.
+type Option struct {
+	name   string
+	server func(s *Server) error
+	client func(c *Client) error
+}
+
+// String returns the name of the option.
+func (o Option) String() string {
+	return o.name
+}
+
+// Helper to configure the server with the option.
+func (o Option) applyServer(s *Server) error {
+	if o.server == nil {
+		return WrapError("the %s option does not apply to servers", nil, o.name)
+	}
+	return o.server(s)
+}
+
+// Helper to configure the client with the option.
+func (o Option) applyClient(c *Client) error {
+	if o.client == nil {
+		return WrapError("the %s option does not apply to clients", nil, o.name)
+	}
+	return o.client(c)
+}
+
+// WithTransport specifies the protocol used to carry messages.
+func WithTransport(transport Transport) Option {
+	return Option{
+		name: "transport",
+		server: func(s *Server) error {
+			s.SetTransport(transport)
+			return nil
+		},
+		client: func(c *Client) error {
+			c.SetTransport(transport)
+			return nil
+		},
+	}
+}
+
+// WithCredentials specifies the TLS credentials used to secure connections,
+// which are loaded with ServerCredentials or ClientCredentials.
+func WithCredentials(creds credentials.TransportCredentials) Option {
+	return Option{
+		name: "credentials",
+		server: func(s *Server) error {
+			s.SetCredentials(creds)
+			return nil
+		},
+		client: func(c *Client) error {
+			c.SetCredentials(creds)
+			return nil
+		},
+	}
+}
+
+// WithKeepalive specifies how often idle gRPC connections are pinged and how
+// long to wait for a ping to be acknowledged before closing the connection.
+// Servers permit clients to ping them as often as the interval.
+func WithKeepalive(interval, timeout time.Duration) Option {
+	return Option{
+		name: "keepalive",
+		server: func(s *Server) error {
+			s.SetKeepalive(interval, timeout)
+			return nil
+		},
+		client: func(c *Client) error {
+			c.SetKeepalive(interval, timeout)
+			return nil
+		},
+	}
+}
+
+// WithMaxMessageSize specifies the largest message in bytes that is received
+// or sent with gRPC.
+func WithMaxMessageSize(size int) Option {
+	return Option{
+		name: "max message size",
+		server: func(s *Server) error {
+			s.SetMaxMessageSize(size)
+			return nil
+		},
+		client: func(c *Client) error {
+			c.SetMaxMessageSize(size)
+			return nil
+		},
+	}
+}
+
+// WithIdentity specifies the name the server reports or the identity the
+// client sends with its messages, which must be unique among clients.
+func WithIdentity(identity string) Option {
+	return Option{
+		name: "identity",
+		server: func(s *Server) error {
+			s.name = identity
+			return nil
+		},
+		client: func(c *Client) error {
+			c.identity = identity
+			return nil
+		},
+	}
+}
+
+// WithUnaryServerInterceptors specifies the chain of interceptors that wrap
+// the unary requests handled by the server.
+func WithUnaryServerInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return Option{
+		name: "unary server interceptors",
+		server: func(s *Server) error {
+			s.SetUnaryInterceptors(interceptors...)
+			return nil
+		},
+	}
+}
+
+// WithStreamServerInterceptors specifies the chain of interceptors that wrap
+// the streaming rpcs handled by the server.
+func WithStreamServerInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return Option{
+		name: "stream server interceptors",
+		server: func(s *Server) error {
+			s.SetStreamInterceptors(interceptors...)
+			return nil
+		},
+	}
+}
+
+// WithUnaryClientInterceptors specifies the chain of interceptors that wrap
+// the unary requests sent by the client.
+func WithUnaryClientInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return Option{
+		name: "unary client interceptors",
+		client: func(c *Client) error {
+			c.SetUnaryInterceptors(interceptors...)
+			return nil
+		},
+	}
+}
+
+// WithStreamClientInterceptors specifies the chain of interceptors that wrap
+// the streaming rpcs sent by the client.
+func WithStreamClientInterceptors(interceptors ...grpc.StreamClientInterceptor) Option {
+	return Option{
+		name: "stream client interceptors",
+		client: func(c *Client) error {
+			c.SetStreamInterceptors(interceptors...)
+			return nil
+		},
+	}
+}
+
+// WithMetrics specifies the metrics the server records requests in, so that the
+// application embedding the server can read or export them. The metrics must
+// be initialized with Init.
+func WithMetrics(metrics *Metrics) Option {
+	return Option{
+		name: "metrics",
+		server: func(s *Server) error {
+			if metrics == nil {
+				return WrapError("metrics must not be nil", nil)
+			}
+			s.SetMetrics(metrics)
+			return nil
+		},
+	}
+}
+
+// WithReplyStrategy specifies how the server constructs its replies.
+func WithReplyStrategy(strategy ReplyStrategy) Option {
+	return Option{
+		name: "reply strategy",
+		server: func(s *Server) error {
+			if strategy == nil {
+				return WrapError("reply strategy must not be nil", nil)
+			}
+			s.SetReplyStrategy(strategy)
+			return nil
+		},
+	}
+}
+
+// WithTokens specifies the tokens that clients must present with each request
+// to the server, see LoadTokens and TokensFromEnv.
+func WithTokens(tokens ...string) Option {
+	return Option{
+		name: "tokens",
+		server: func(s *Server) error {
+			s.SetTokens(tokens...)
+			return nil
+		},
+	}
+}
+
+// WithToken specifies the token the client presents with each request and
+// whether it is sent as a bearer token or used to sign the request.
+func WithToken(token string, scheme AuthScheme) Option {
+	return Option{
+		name: "token",
+		client: func(c *Client) error {
+			c.SetToken(token, scheme)
+			return nil
+		},
+	}
+}
+
+// WithDialer specifies how the client connects to the server.
+func WithDialer(dialer Dialer) Option {
+	return Option{
+		name: "dialer",
+		client: func(c *Client) error {
+			c.SetDialer(dialer)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package echo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestServerOptions(t *testing.T) {
+	metrics := new(Metrics)
+	metrics.Init()
+
+	s, err := NewServer("", "test",
+		WithTransport(TCPTransport{}),
+		WithIdentity("echo-1"),
+		WithMetrics(metrics),
+		WithReplyStrategy(EchoReply),
+		WithKeepalive(time.Minute, 10*time.Second),
+		WithMaxMessageSize(1024),
+		WithUnaryServerInterceptors(UnaryRecovery),
+	)
+	if err != nil {
+		t.Fatalf("could not create server: %s", err)
+	}
+
+	if s.Transport().String() != "tcp" || s.name != "echo-1" || s.Metrics() != metrics {
+		t.Errorf("server was not configured by the options")
+	}
+
+	if s.keepalive != time.Minute || s.maxSize != 1024 || len(s.unary) != 1 {
+		t.Errorf("grpc server options were not configured by the options")
+	}
+
+	// Client only options cannot configure a server
+	if _, err := NewServer("", "test", WithDialer(nil)); err == nil {
+		t.Error("expected dialer option to be rejected by the server")
+	}
+
+	if _, err := NewServer("", "test", WithMetrics(nil)); err == nil {
+		t.Error("expected nil metrics to be rejected")
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	c, err := NewClient("localhost:4157", "test",
+		WithTransport(HTTPTransport{}),
+		WithIdentity("client-1"),
+		WithKeepalive(time.Minute, 10*time.Second),
+		WithMaxMessageSize(1024),
+		WithUnaryClientInterceptors(UnaryClientLogger),
+	)
+	if err != nil {
+		t.Fatalf("could not create client: %s", err)
+	}
+
+	if c.transport.String() != "http" || c.identity != "client-1" {
+		t.Errorf("client was not configured by the options")
+	}
+
+	if c.keepalive != time.Minute || c.maxSize != 1024 || len(c.unary) != 1 {
+		t.Errorf("grpc dial options were not configured by the options")
+	}
+
+	// Server only options cannot configure a client
+	if _, err := NewClient("localhost:4157", "test", WithReplyStrategy(EchoReply)); err == nil {
+		t.Error("expected reply strategy option to be rejected by the client")
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	s, _ := NewServer("", "test", WithReplyStrategy(EchoReply), WithMaxMessageSize(1024))
+	addr, stop := runServer(t, s)
+	defer stop()
+
+	c, _ := NewClient(addr, "test", WithKeepalive(time.Minute, 10*time.Second))
+	if err := c.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer c.Close()
+
+	if err := c.Send("hello"); err != nil {
+		t.Fatalf("could not send message: %s", err)
+	}
+
+	if err := c.Send(strings.Repeat("x", 2048)); statusCode(err) != codes.ResourceExhausted {
+		t.Errorf("expected message larger than the limit to be rejected, got %v", err)
+	}
+}
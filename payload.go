@@ -0,0 +1,217 @@
+package echo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxPayloadSize is the largest payload the server will reply with, keeping
+// replies under the default 4MB message limit of gRPC.
+const MaxPayloadSize = 4*1024*1024 - 1024
+
+// PayloadSize generates the sizes of message payloads in bytes.
+type PayloadSize interface {
+	Next() int      // the size of the next payload
+	String() string // the specification of the payload size
+}
+
+// ParsePayloadSize parses a payload size specification, which is one of:
+//
+//	1024            fixed size in bytes (suffixes K and M are allowed)
+//	64-4K           uniformly distributed between min and max inclusive
+//	normal:1K,256   normally distributed with mean and standard deviation
+//	exp:1K          exponentially distributed with the mean size
+//
+// An empty specification means messages have no payload.
+func ParsePayloadSize(spec string) (PayloadSize, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return FixedSize(0), nil
+	}
+
+	// Distributions are specified as name:arg,arg
+	if parts := strings.SplitN(spec, ":", 2); len(parts) == 2 {
+		args, err := ParseSizes(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(parts[0]) {
+		case "normal":
+			if len(args) != 2 {
+				return nil, fmt.Errorf("normal payload size requires mean and stddev: %q", spec)
+			}
+			return &normalSize{mean: args[0], stddev: args[1]}, nil
+		case "exp":
+			if len(args) != 1 {
+				return nil, fmt.Errorf("exp payload size requires a mean: %q", spec)
+			}
+			return &expSize{mean: args[0]}, nil
+		default:
+			return nil, fmt.Errorf("unknown payload size distribution %q", parts[0])
+		}
+	}
+
+	// Uniform ranges are specified as min-max
+	if parts := strings.SplitN(spec, "-", 2); len(parts) == 2 {
+		min, err := ParseBytes(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		max, err := ParseBytes(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if max < min {
+			return nil, fmt.Errorf("payload size range %q has max less than min", spec)
+		}
+		return &uniformSize{min: min, max: max}, nil
+	}
+
+	size, err := ParseBytes(spec)
+	if err != nil {
+		return nil, err
+	}
+	return FixedSize(size), nil
+}
+
+// ParseSizes parses a comma separated list of sizes in bytes.
+func ParseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, part := range strings.Split(s, ",") {
+		size, err := ParseBytes(part)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// ParseBytes parses a size in bytes with an optional K or M suffix (powers
+// of 1024) and an optional trailing B, e.g. 512, 4K, 4KB or 1MB.
+func ParseBytes(s string) (int, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+
+	unit := 1
+	switch {
+	case strings.HasSuffix(s, "K"):
+		unit = 1024
+		s = strings.TrimSuffix(s, "K")
+	case strings.HasSuffix(s, "M"):
+		unit = 1024 * 1024
+		s = strings.TrimSuffix(s, "M")
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("could not parse size %q", s)
+	}
+	return n * unit, nil
+}
+
+// payloads is the pattern that payloads are sliced from, grown on demand so
+// that the pattern is not filled every time a message is sent.
+var payloads struct {
+	sync.RWMutex
+	pattern []byte
+}
+
+// Payload returns a payload of the specified number of bytes. The contents
+// are a repeating pattern so that replies can be checked for integrity.
+// Payloads share the pattern so they must not be modified.
+func Payload(size int) []byte {
+	if size <= 0 {
+		return nil
+	}
+
+	payloads.RLock()
+	pattern := payloads.pattern
+	payloads.RUnlock()
+
+	if len(pattern) < size {
+		payloads.Lock()
+		if len(payloads.pattern) < size {
+			// Grow the pattern geometrically so it is filled only a few times
+			n := 2 * len(payloads.pattern)
+			if n < size {
+				n = size
+			}
+
+			grown := make([]byte, n)
+			for i := range grown {
+				grown[i] = 'a' + byte(i%26)
+			}
+			payloads.pattern = grown
+		}
+		pattern = payloads.pattern
+		payloads.Unlock()
+	}
+
+	// Limit the capacity so that appending to a payload copies it
+	return pattern[:size:size]
+}
+
+//===========================================================================
+// Payload Size Generators
+//===========================================================================
+
+// FixedSize generates payloads that are always the same size.
+type FixedSize int
+
+// Next returns the fixed size.
+func (s FixedSize) Next() int {
+	return int(s)
+}
+
+// String returns the fixed size in bytes.
+func (s FixedSize) String() string {
+	return strconv.Itoa(int(s))
+}
+
+// uniformSize generates payload sizes uniformly in a range.
+type uniformSize struct {
+	min, max int
+}
+
+func (s *uniformSize) Next() int {
+	return s.min + rand.Intn(s.max-s.min+1)
+}
+
+func (s *uniformSize) String() string {
+	return fmt.Sprintf("%d-%d", s.min, s.max)
+}
+
+// normalSize generates normally distributed payload sizes, never less than 0.
+type normalSize struct {
+	mean, stddev int
+}
+
+func (s *normalSize) Next() int {
+	size := rand.NormFloat64()*float64(s.stddev) + float64(s.mean)
+	return int(math.Max(0, math.Round(size)))
+}
+
+func (s *normalSize) String() string {
+	return fmt.Sprintf("normal:%d,%d", s.mean, s.stddev)
+}
+
+// expSize generates exponentially distributed payload sizes.
+type expSize struct {
+	mean int
+}
+
+func (s *expSize) Next() int {
+	return int(math.Round(rand.ExpFloat64() * float64(s.mean)))
+}
+
+func (s *expSize) String() string {
+	return fmt.Sprintf("exp:%d", s.mean)
+}
@@ -0,0 +1,27 @@
+package echo
+
+import (
+	"testing"
+)
+
+func TestPayload(t *testing.T) {
+	for _, size := range []int{0, 1, 26, 1000, 64 * 1024, 10} {
+		payload := Payload(size)
+		if len(payload) != size || cap(payload) != size {
+			t.Errorf("expected payload of %d bytes, got len %d cap %d", size, len(payload), cap(payload))
+			continue
+		}
+
+		for i, b := range payload {
+			if b != 'a'+byte(i%26) {
+				t.Errorf("payload of %d bytes has %q at %d", size, b, i)
+				break
+			}
+		}
+	}
+
+	// Payloads no larger than the pattern are not filled or allocated again
+	if allocs := testing.AllocsPerRun(100, func() { Payload(64 * 1024) }); allocs != 0 {
+		t.Errorf("expected payloads to reuse the pattern, got %v allocations", allocs)
+	}
+}
@@ -0,0 +1,155 @@
+package echo
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// PrometheusPath is the path the metrics are exposed on for scraping.
+const PrometheusPath = "/metrics"
+
+// prometheusContentType is the content type of the text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WritePrometheus writes the metrics to w in the Prometheus text exposition
+// format so that the server can be scraped by dashboards.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.RLock()
+	defer m.RUnlock()
+
+	buf := bufio.NewWriter(w)
+	metric := func(name, kind, help string) {
+		buf.WriteString("# HELP " + name + " " + help + "\n")
+		buf.WriteString("# TYPE " + name + " " + kind + "\n")
+	}
+	sample := func(name, labels string, value string) {
+		buf.WriteString(name)
+		if labels != "" {
+			buf.WriteString("{" + labels + "}")
+		}
+		buf.WriteString(" " + value + "\n")
+	}
+
+	metric("echo_requests_total", "counter", "Number of requests handled by rpc method.")
+	for _, method := range sortedKeys(m.requests) {
+		sample("echo_requests_total", label("method", method), formatUint(m.requests[method]))
+	}
+
+	metric("echo_messages_received_total", "counter", "Number of messages received from all clients.")
+	sample("echo_messages_received_total", "", formatUint(m.totalAccesses()))
+
+	metric("echo_client_accesses_total", "counter", "Number of messages received per client.")
+	for _, client := range sortedKeys(m.accesses) {
+		sample("echo_client_accesses_total", label("client", client), formatUint(m.accesses[client]))
+	}
+
+	metric("echo_clients", "gauge", "Number of clients that have accessed the server.")
+	sample("echo_clients", "", strconv.Itoa(len(m.accesses)))
+
+	metric("echo_requests_in_flight", "gauge", "Number of requests currently being handled.")
+	sample("echo_requests_in_flight", "", strconv.FormatInt(m.inflight, 10))
+
+	metric("echo_errors_total", "counter", "Number of failed requests by gRPC status code.")
+	codes := make([]string, 0, len(m.errors))
+	counts := make(map[string]uint64, len(m.errors))
+	for code, count := range m.errors {
+		codes = append(codes, code.String())
+		counts[code.String()] = count
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		sample("echo_errors_total", label("code", code), formatUint(counts[code]))
+	}
+
+	metric("echo_expired_total", "counter", "Number of requests dropped because their deadline passed.")
+	sample("echo_expired_total", "", formatUint(m.expired))
+
+	metric("echo_rejected_total", "counter", "Number of requests rejected because they were not authenticated.")
+	sample("echo_rejected_total", "", formatUint(m.rejected))
+
+	metric("echo_faults_injected_total", "counter", "Number of faults injected into requests by kind.")
+	for _, fault := range sortedKeys(m.faults) {
+		sample("echo_faults_injected_total", label("fault", fault), formatUint(m.faults[fault]))
+	}
+
+	metric("echo_handler_duration_seconds", "histogram", "Time spent handling requests.")
+	var cumulative uint64
+	for i, bound := range HandlerBuckets {
+		cumulative += m.handler[i]
+		sample("echo_handler_duration_seconds_bucket", label("le", formatFloat(bound.Seconds())), formatUint(cumulative))
+	}
+	cumulative += m.handler[len(HandlerBuckets)]
+	sample("echo_handler_duration_seconds_bucket", label("le", "+Inf"), formatUint(cumulative))
+	sample("echo_handler_duration_seconds_sum", "", formatFloat(m.handled.Seconds()))
+	sample("echo_handler_duration_seconds_count", "", formatUint(cumulative))
+
+	return buf.Flush()
+}
+
+// ServeHTTP implements http.Handler to expose the metrics to Prometheus.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	if err := m.WritePrometheus(w); err != nil {
+		warne(err)
+	}
+}
+
+// serveMetrics exposes the server metrics on the address until the context
+// is canceled, returning an error if the address cannot be bound.
+func serveMetrics(ctx context.Context, addr string, m *Metrics) error {
+	sock, err := net.Listen("tcp", addr)
+	if err != nil {
+		return WrapError("could not listen for metrics on '%s'", err, addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(PrometheusPath, m)
+	srv := &http.Server{Handler: mux}
+
+	go func() {
+		if err := srv.Serve(sock); err != nil && err != http.ErrServerClosed {
+			warne(err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	status("serving metrics on http://%s%s", sock.Addr(), PrometheusPath)
+	return nil
+}
+
+// Helper to format a label with the value escaped for the exposition format.
+func label(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return name + `="` + value + `"`
+}
+
+// Helper to format a counter value.
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+// Helper to format a float value.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Helper to return the keys of the map in sorted order.
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
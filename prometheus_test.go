@@ -0,0 +1,144 @@
+package echo
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// sample is a single line of the exposition format.
+type sample struct {
+	name   string
+	labels string
+	value  float64
+}
+
+// parse the samples of the exposition format in order, failing the test if
+// a line is malformed.
+func parse(t *testing.T, text string) []sample {
+	var samples []sample
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# HELP ") || strings.HasPrefix(line, "# TYPE ") {
+			continue
+		}
+
+		idx := strings.LastIndex(line, " ")
+		if idx < 0 {
+			t.Fatalf("malformed sample %q", line)
+		}
+
+		value, err := strconv.ParseFloat(line[idx+1:], 64)
+		if err != nil {
+			t.Fatalf("malformed value of %q: %s", line, err)
+		}
+
+		s := sample{name: line[:idx], value: value}
+		if i := strings.Index(s.name, "{"); i >= 0 {
+			if !strings.HasSuffix(s.name, "}") {
+				t.Fatalf("malformed labels of %q", line)
+			}
+			s.name, s.labels = s.name[:i], s.name[i+1:len(s.name)-1]
+		}
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+func TestWritePrometheus(t *testing.T) {
+	m := new(Metrics)
+	m.Init()
+
+	for _, elapsed := range []time.Duration{50 * time.Microsecond, 3 * time.Millisecond, 20 * time.Second} {
+		m.Begin("respond")
+		m.Increment("client-1")
+		var err error
+		if elapsed > 10*time.Second {
+			err = gstatus.Error(codes.Unavailable, "too slow")
+		}
+		m.Finish(elapsed, err)
+	}
+	m.Increment(`quoted "client" \ with` + "\nnewline")
+	m.Inject("delay")
+	m.Reject()
+
+	var buf strings.Builder
+	if err := m.WritePrometheus(&buf); err != nil {
+		t.Fatalf("could not write metrics: %s", err)
+	}
+
+	samples := parse(t, buf.String())
+	values := make(map[string]float64, len(samples))
+	for _, s := range samples {
+		values[s.name+"{"+s.labels+"}"] = s.value
+	}
+
+	for key, expected := range map[string]float64{
+		`echo_requests_total{method="respond"}`:                                   3,
+		`echo_messages_received_total{}`:                                          4,
+		`echo_client_accesses_total{client="client-1"}`:                           3,
+		`echo_client_accesses_total{client="quoted \"client\" \\ with\nnewline"}`: 1,
+		`echo_clients{}`:                                    2,
+		`echo_requests_in_flight{}`:                         0,
+		`echo_errors_total{code="Unavailable"}`:             1,
+		`echo_rejected_total{}`:                             1,
+		`echo_faults_injected_total{fault="delay"}`:         1,
+		`echo_handler_duration_seconds_bucket{le="0.0001"}`: 1,
+		`echo_handler_duration_seconds_bucket{le="0.0025"}`: 1,
+		`echo_handler_duration_seconds_bucket{le="0.005"}`:  2,
+		`echo_handler_duration_seconds_bucket{le="10"}`:     2,
+		`echo_handler_duration_seconds_bucket{le="+Inf"}`:   3,
+		`echo_handler_duration_seconds_sum{}`:               20.00305,
+		`echo_handler_duration_seconds_count{}`:             3,
+	} {
+		if value, ok := values[key]; !ok {
+			t.Errorf("expected sample %s", key)
+		} else if value != expected {
+			t.Errorf("expected %s to be %v, got %v", key, expected, value)
+		}
+	}
+
+	// Buckets are cumulative and end with +Inf, which equals the count
+	var buckets []sample
+	for _, s := range samples {
+		if s.name == "echo_handler_duration_seconds_bucket" {
+			buckets = append(buckets, s)
+		}
+	}
+
+	if len(buckets) != len(HandlerBuckets)+1 {
+		t.Fatalf("expected %d buckets, got %d", len(HandlerBuckets)+1, len(buckets))
+	}
+
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i].value < buckets[i-1].value {
+			t.Errorf("expected bucket %s to be cumulative, got %v after %v", buckets[i].labels, buckets[i].value, buckets[i-1].value)
+		}
+	}
+
+	last := buckets[len(buckets)-1]
+	if last.labels != `le="+Inf"` || last.value != values["echo_handler_duration_seconds_count{}"] {
+		t.Errorf("expected last bucket to be +Inf with the count, got %s %v", last.labels, last.value)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	for value, expected := range map[string]string{
+		"respond":    `method="respond"`,
+		`say "hi"`:   `method="say \"hi\""`,
+		`back\slash`: `method="back\\slash"`,
+		"new\nline":  `method="new\nline"`,
+		"\\\"\n":     `method="\\\"\n"`,
+		"":           `method=""`,
+	} {
+		if label := label("method", value); label != expected {
+			t.Errorf("expected %q to be labeled %s, got %s", value, expected, label)
+		}
+	}
+}
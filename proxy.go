@@ -0,0 +1,382 @@
+package echo
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultStall is how long the proxy holds stalled data if not specified.
+const DefaultStall = 500 * time.Millisecond
+
+// Size of the chunks of data read from connections by the proxy, and the
+// number of chunks buffered in each direction before reads block.
+const (
+	proxyChunkSize = 32 * 1024
+	proxyBacklog   = 64
+)
+
+// Impairment specifies how the proxy degrades the network between clients and
+// the server. Latency and jitter are added in each direction, so the round
+// trip time is increased by twice the latency. The zero value forwards data
+// without impairment.
+type Impairment struct {
+	Latency   time.Duration // one-way delay added to the data forwarded
+	Jitter    time.Duration // maximum random variation added to the latency
+	Bandwidth int           // bytes per second in each direction, 0 is unlimited
+	StallRate float64       // fraction of chunks of data that are held before forwarding
+	Stall     time.Duration // how long stalled chunks of data are held
+	ResetRate float64       // fraction of chunks of data that reset the connection
+}
+
+// String returns a summary of the impairment.
+func (i Impairment) String() string {
+	var impairs []string
+	if i.Latency > 0 || i.Jitter > 0 {
+		impairs = append(impairs, fmt.Sprintf("%s latency with %s jitter", i.Latency, i.Jitter))
+	}
+
+	if i.Bandwidth > 0 {
+		impairs = append(impairs, fmt.Sprintf("%d bytes/sec bandwidth", i.Bandwidth))
+	}
+
+	if i.StallRate > 0 {
+		impairs = append(impairs, fmt.Sprintf("%s %s stalls", formatPercent(i.StallRate), i.Stall))
+	}
+
+	if i.ResetRate > 0 {
+		impairs = append(impairs, fmt.Sprintf("%s resets", formatPercent(i.ResetRate)))
+	}
+
+	if len(impairs) == 0 {
+		return "no impairment"
+	}
+	return strings.Join(impairs, ", ")
+}
+
+// ParseBandwidth parses a bandwidth in bytes per second with an optional K or
+// M suffix and an optional trailing /s, e.g. 512K or 1MB/s.
+func ParseBandwidth(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return ParseBytes(strings.TrimSuffix(s, "/s"))
+}
+
+//===========================================================================
+// Chaos Proxy
+//===========================================================================
+
+// NewProxy creates a proxy that forwards connections accepted on the listen
+// address to the upstream address, impairing the data that is forwarded.
+func NewProxy(listen, upstream string, impair Impairment) (*Proxy, error) {
+	p := new(Proxy)
+	if err := p.Init(listen, upstream, impair); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Proxy forwards tcp connections between clients and the server while
+// injecting latency, jitter, bandwidth limits, stalls and connection resets so
+// that benchmarks can be run under reproducible network impairment.
+type Proxy struct {
+	listen   string                // address to accept client connections on
+	upstream string                // address of the server to forward connections to
+	impair   Impairment            // how the forwarded data is degraded
+	timeout  time.Duration         // how long to wait to connect to the server
+	mu       sync.Mutex            // protects the open connections
+	conns    map[net.Conn]struct{} // connections to close when the proxy stops
+	wg       sync.WaitGroup        // waits for forwarded connections to close
+
+	// counters are accessed atomically
+	nConns     uint64 // number of client connections accepted
+	nActive    int64  // number of connections currently being forwarded
+	nFailed    uint64 // number of connections that could not reach the server
+	nBytesUp   uint64 // number of bytes forwarded from clients to the server
+	nBytesDown uint64 // number of bytes forwarded from the server to clients
+	nStalls    uint64 // number of chunks of data that were stalled
+	nResets    uint64 // number of connections that were reset
+	started    int64  // unix nanoseconds the proxy started
+}
+
+// Init the proxy, returning an error if the impairment is invalid.
+func (p *Proxy) Init(listen, upstream string, impair Impairment) error {
+	if impair.StallRate < 0 || impair.StallRate > 1 {
+		return fmt.Errorf("stall rate %v is not between 0 and 1", impair.StallRate)
+	}
+
+	if impair.ResetRate < 0 || impair.ResetRate > 1 {
+		return fmt.Errorf("reset rate %v is not between 0 and 1", impair.ResetRate)
+	}
+
+	if impair.StallRate > 0 && impair.Stall <= 0 {
+		impair.Stall = DefaultStall
+	}
+
+	p.listen = listen
+	p.upstream = upstream
+	p.impair = impair
+	p.timeout = 5 * time.Second
+	p.conns = make(map[net.Conn]struct{})
+	return nil
+}
+
+// Run the proxy on its listen address until the context is canceled.
+func (p *Proxy) Run(ctx context.Context) error {
+	sock, err := net.Listen("tcp", p.listen)
+	if err != nil {
+		return WrapError("could not listen on '%s'", err, p.listen)
+	}
+
+	status("proxying %s to %s with %s", sock.Addr(), p.upstream, p.impair)
+	return p.Serve(ctx, sock)
+}
+
+// Serve connections accepted on the listener until the context is canceled,
+// at which point all forwarded connections are closed.
+func (p *Proxy) Serve(ctx context.Context, sock net.Listener) error {
+	atomic.StoreInt64(&p.started, time.Now().UnixNano())
+
+	// Close the listener when the proxy is stopped so that accept returns
+	go func() {
+		<-ctx.Done()
+		sock.Close()
+	}()
+
+	for {
+		conn, err := sock.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				p.close()
+				return nil
+			}
+			return err
+		}
+
+		atomic.AddUint64(&p.nConns, 1)
+		p.wg.Add(1)
+		go p.forward(conn)
+	}
+}
+
+// close all forwarded connections and wait for them to finish.
+func (p *Proxy) close() {
+	status("stopping proxy, closing %d connections", atomic.LoadInt64(&p.nActive))
+	p.mu.Lock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// forward data between the client and a new connection to the server until
+// both sides have finished sending or the connection is reset.
+func (p *Proxy) forward(client net.Conn) {
+	defer p.wg.Done()
+	defer client.Close()
+
+	server, err := net.DialTimeout("tcp", p.upstream, p.timeout)
+	if err != nil {
+		atomic.AddUint64(&p.nFailed, 1)
+		warn("could not connect to %s: %s", p.upstream, err)
+		return
+	}
+	defer server.Close()
+
+	if !p.track(client, server) {
+		return
+	}
+	defer p.untrack(client, server)
+
+	atomic.AddInt64(&p.nActive, 1)
+	defer atomic.AddInt64(&p.nActive, -1)
+	debug("forwarding connection from %s to %s", client.RemoteAddr(), p.upstream)
+
+	var once sync.Once
+	reset := func() {
+		once.Do(func() {
+			atomic.AddUint64(&p.nResets, 1)
+			debug("resetting connection from %s", client.RemoteAddr())
+			for _, conn := range []net.Conn{client, server} {
+				if tc, ok := conn.(*net.TCPConn); ok {
+					tc.SetLinger(0)
+				}
+				conn.Close()
+			}
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.pipe(server, client, &p.nBytesUp, reset)
+		close(done)
+	}()
+	p.pipe(client, server, &p.nBytesDown, reset)
+	<-done
+}
+
+// track the connections so that they are closed when the proxy stops,
+// returning false if the proxy has already stopped.
+func (p *Proxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conns == nil {
+		return false
+	}
+
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *Proxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+// chunk is data read from a connection and the time it was read.
+type chunk struct {
+	data []byte
+	at   time.Time
+}
+
+// pipe copies data from the source to the destination, delaying each chunk of
+// data by the latency and jitter from when it was read, limiting the rate it
+// is written by the bandwidth and randomly stalling or resetting the
+// connection. Chunks are always written in the order they were read.
+func (p *Proxy) pipe(dst, src net.Conn, counter *uint64, reset func()) {
+	chunks := make(chan chunk, proxyBacklog)
+	go func() {
+		defer close(chunks)
+		for {
+			buf := make([]byte, proxyChunkSize)
+			n, err := src.Read(buf)
+			if n > 0 {
+				chunks <- chunk{data: buf[:n], at: time.Now()}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Unblock the reader if the destination fails before the source is done
+	defer func() {
+		for range chunks {
+		}
+	}()
+
+	var next time.Time
+	for c := range chunks {
+		// Data cannot be delivered before data that was read earlier
+		deliver := c.at.Add(p.latency())
+		if deliver.Before(next) {
+			deliver = next
+		}
+		time.Sleep(time.Until(deliver))
+
+		if p.impair.StallRate > 0 && rand.Float64() < p.impair.StallRate {
+			atomic.AddUint64(&p.nStalls, 1)
+			time.Sleep(p.impair.Stall)
+		}
+
+		if p.impair.ResetRate > 0 && rand.Float64() < p.impair.ResetRate {
+			reset()
+			return
+		}
+
+		if _, err := dst.Write(c.data); err != nil {
+			debug("could not forward data: %s", err)
+			dst.Close()
+			src.Close()
+			return
+		}
+		atomic.AddUint64(counter, uint64(len(c.data)))
+
+		// The next chunk can be sent once this one has been transmitted
+		next = time.Now()
+		if p.impair.Bandwidth > 0 {
+			next = next.Add(time.Duration(len(c.data)) * time.Second / time.Duration(p.impair.Bandwidth))
+		}
+	}
+
+	// The source finished sending, so finish sending to the destination
+	if tc, ok := dst.(*net.TCPConn); ok {
+		tc.CloseWrite()
+	} else {
+		dst.Close()
+	}
+}
+
+// latency returns the delay of the next chunk of data with random jitter.
+func (p *Proxy) latency() time.Duration {
+	latency := p.impair.Latency
+	if p.impair.Jitter > 0 {
+		latency += time.Duration(rand.Int63n(int64(2*p.impair.Jitter)+1)) - p.impair.Jitter
+	}
+
+	if latency < 0 {
+		return 0
+	}
+	return latency
+}
+
+// Serialize the counters of the proxy to a map.
+func (p *Proxy) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	data["listen"] = p.listen
+	data["upstream"] = p.upstream
+	data["impairment"] = p.impair.String()
+	data["latency (nsec)"] = p.impair.Latency.Nanoseconds()
+	data["jitter (nsec)"] = p.impair.Jitter.Nanoseconds()
+	data["bandwidth (bytes/sec)"] = p.impair.Bandwidth
+	data["stall rate"] = p.impair.StallRate
+	data["stall (nsec)"] = p.impair.Stall.Nanoseconds()
+	data["reset rate"] = p.impair.ResetRate
+	data["connections"] = atomic.LoadUint64(&p.nConns)
+	data["active"] = atomic.LoadInt64(&p.nActive)
+	data["failed"] = atomic.LoadUint64(&p.nFailed)
+	data["bytes up"] = atomic.LoadUint64(&p.nBytesUp)
+	data["bytes down"] = atomic.LoadUint64(&p.nBytesDown)
+	data["stalls"] = atomic.LoadUint64(&p.nStalls)
+	data["resets"] = atomic.LoadUint64(&p.nResets)
+
+	if started := atomic.LoadInt64(&p.started); started > 0 {
+		data["duration"] = time.Since(time.Unix(0, started)).String()
+	}
+	return data
+}
+
+// String returns a summary of the proxy counters.
+func (p *Proxy) String() string {
+	return fmt.Sprintf(
+		"%d connections, %d bytes up and %d bytes down, %d stalls and %d resets",
+		atomic.LoadUint64(&p.nConns), atomic.LoadUint64(&p.nBytesUp),
+		atomic.LoadUint64(&p.nBytesDown), atomic.LoadUint64(&p.nStalls),
+		atomic.LoadUint64(&p.nResets),
+	)
+}
+
+// Write the counters of the proxy to the path, appending the JSON as a line.
+func (p *Proxy) Write(path string) error {
+	if path == "" {
+		return nil
+	}
+	return appendJSON(path, p.Serialize())
+}
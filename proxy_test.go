@@ -0,0 +1,118 @@
+package echo
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// runProxy serves the proxy in front of the address on a random local port,
+// returning the address to connect to and a function that stops the proxy.
+func runProxy(t *testing.T, upstream string, impair Impairment) (*Proxy, string, func()) {
+	p, err := NewProxy("", upstream, impair)
+	if err != nil {
+		t.Fatalf("could not create proxy: %s", err)
+	}
+
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Serve(ctx, sock)
+	}()
+
+	stop := func() {
+		cancel()
+		if err := <-errc; err != nil {
+			t.Errorf("proxy stopped with error: %s", err)
+		}
+	}
+
+	return p, sock.Addr().String(), stop
+}
+
+func TestProxyLatency(t *testing.T) {
+	for _, name := range Transports() {
+		t.Run(name, func(t *testing.T) {
+			transport, _ := ParseTransport(name)
+			s, _ := NewServer("", "test")
+			s.SetTransport(transport)
+			addr, stopServer := runServer(t, s)
+			defer stopServer()
+
+			p, proxied, stopProxy := runProxy(t, addr, Impairment{Latency: 10 * time.Millisecond})
+			defer stopProxy()
+
+			c, _ := NewClient(proxied, "test")
+			c.SetTransport(transport)
+			if err := c.Connect(5 * time.Second); err != nil {
+				t.Fatalf("could not connect: %s", err)
+			}
+			defer c.Close()
+
+			// Connect before timing since connection setup adds round trips
+			if err := c.Send("hello"); err != nil {
+				t.Fatalf("could not send message: %s", err)
+			}
+
+			start := time.Now()
+			if err := c.Send("hello"); err != nil {
+				t.Fatalf("could not send message: %s", err)
+			}
+
+			// The latency is added in each direction
+			if rtt := time.Since(start); rtt < 20*time.Millisecond {
+				t.Errorf("expected round trip of at least 20ms, took %s", rtt)
+			}
+
+			data := p.Serialize()
+			if data["bytes up"].(uint64) == 0 || data["bytes down"].(uint64) == 0 {
+				t.Errorf("expected bytes to be forwarded in both directions: %s", p)
+			}
+		})
+	}
+}
+
+func TestProxyResets(t *testing.T) {
+	s, _ := NewServer("", "test")
+	s.SetTransport(TCPTransport{})
+	addr, stopServer := runServer(t, s)
+	defer stopServer()
+
+	p, proxied, stopProxy := runProxy(t, addr, Impairment{ResetRate: 1})
+	defer stopProxy()
+
+	c, _ := NewClient(proxied, "test")
+	c.SetTransport(TCPTransport{})
+	if err := c.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer c.Close()
+
+	if err := c.Send("hello"); err == nil {
+		t.Error("expected message to fail when the connection is reset")
+	}
+
+	if resets := p.Serialize()["resets"].(uint64); resets != 1 {
+		t.Errorf("expected 1 reset, got %d", resets)
+	}
+
+	if n := s.metrics.Accesses(); n != 0 {
+		t.Errorf("expected no messages to reach the server, got %d", n)
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	for spec, expected := range map[string]int{"": 0, "512": 512, "1M/s": 1024 * 1024, "64KB/s": 64 * 1024} {
+		bandwidth, err := ParseBandwidth(spec)
+		if err != nil || bandwidth != expected {
+			t.Errorf("expected %q to parse as %d, got %d (%v)", spec, expected, bandwidth, err)
+		}
+	}
+}
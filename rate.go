@@ -0,0 +1,85 @@
+package echo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Arrivals describe how requests are scheduled by an open-loop benchmark.
+const (
+	ConstantArrivals Arrivals = iota
+	PoissonArrivals
+)
+
+// DefaultOutstanding is the maximum number of in-flight requests per client in
+// an open-loop benchmark; requests scheduled beyond this limit are dropped.
+const DefaultOutstanding = 1024
+
+var arrivalsStrings = [...]string{"constant", "poisson"}
+
+// Arrivals is the distribution of request send times in an open-loop benchmark.
+type Arrivals uint8
+
+// ParseArrivals returns the arrivals from its string representation.
+func ParseArrivals(s string) (Arrivals, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range arrivalsStrings {
+		if s == name {
+			return Arrivals(i), nil
+		}
+	}
+	return ConstantArrivals, fmt.Errorf("unknown arrivals %q, use constant or poisson", s)
+}
+
+// String returns a human readable representation of the arrivals.
+func (a Arrivals) String() string {
+	if int(a) < len(arrivalsStrings) {
+		return arrivalsStrings[a]
+	}
+	return fmt.Sprintf("arrivals(%d)", a)
+}
+
+// Next returns the time between requests sent at the specified mean interval.
+func (a Arrivals) Next(interval time.Duration) time.Duration {
+	if a == PoissonArrivals {
+		return time.Duration(rand.ExpFloat64() * float64(interval))
+	}
+	return interval
+}
+
+// ParseRate parses a request rate such as 5000/s, 300/m or 5000 (per second)
+// and returns the rate in requests per second.
+func ParseRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	unit := time.Second
+
+	if parts := strings.SplitN(s, "/", 2); len(parts) == 2 {
+		s = parts[0]
+		switch strings.ToLower(strings.TrimSpace(parts[1])) {
+		case "s", "sec", "second":
+			unit = time.Second
+		case "m", "min", "minute":
+			unit = time.Minute
+		case "ms":
+			unit = time.Millisecond
+		default:
+			return 0, fmt.Errorf("unknown rate unit %q", parts[1])
+		}
+	}
+
+	rate, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse rate %q", s)
+	}
+
+	// Rates that are not positive and finite cannot be scheduled
+	rate = rate * float64(time.Second) / float64(unit)
+	if math.IsNaN(rate) || math.IsInf(rate, 0) || rate <= 0 {
+		return 0, fmt.Errorf("rate %q is not a finite number greater than zero", s)
+	}
+	return rate, nil
+}
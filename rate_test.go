@@ -0,0 +1,80 @@
+package echo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	for spec, expected := range map[string]float64{
+		"5000":    5000,
+		"5000/s":  5000,
+		"300/m":   5,
+		"2/ms":    2000,
+		"0.5/sec": 0.5,
+	} {
+		rate, err := ParseRate(spec)
+		if err != nil || rate != expected {
+			t.Errorf("expected %q to parse as %v, got %v (%v)", spec, expected, rate, err)
+		}
+	}
+
+	for _, spec := range []string{"", "0", "-5/s", "inf", "+Inf/s", "NaN", "1e309", "1e307/ms", "5/h"} {
+		if _, err := ParseRate(spec); err == nil {
+			t.Errorf("expected %q to fail to parse", spec)
+		}
+	}
+}
+
+func TestRunOpenRate(t *testing.T) {
+	c, _ := NewClient("localhost:4157", "test")
+
+	// Rates too high to schedule must fail rather than spin past the duration
+	c.SetRate(1e12, ConstantArrivals, 1)
+	done := make(chan error, 1)
+	go func() { done <- c.runOpen(10 * time.Millisecond) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected a rate too high to schedule to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("open-loop benchmark did not stop")
+	}
+}
+
+func TestRunOpenBatch(t *testing.T) {
+	s, _ := NewServer("", "test")
+	addr, stop := runServer(t, s)
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The rate counts messages, so each streaming rpc uses a batch of the schedule
+	b, _ := NewBenchmark(addr, "test", 1)
+	b.SetMode(ServerStream, 10)
+	b.SetRate(1000, ConstantArrivals, 0)
+	if err := b.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer b.Close()
+
+	if err := b.Run(200*time.Millisecond, filepath.Join(dir, "results.json")); err != nil {
+		t.Fatalf("could not run benchmark: %s", err)
+	}
+
+	c := b.clients[0]
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if messages := c.messages + c.nDropped; messages < 100 || messages > 300 {
+		t.Errorf("expected about 200 messages at 1000 msg/sec for 200ms, got %d", messages)
+	}
+}
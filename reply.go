@@ -0,0 +1,119 @@
+package echo
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/bbengfort/echo/msg"
+)
+
+// DefaultReply is the reply strategy used by the server if none is specified.
+const DefaultReply = "fixed"
+
+// ReplyStrategy constructs the message and payload of the server's reply to
+// the specified request; seq is the number of messages received by the server.
+// The server sets the sender of the reply.
+type ReplyStrategy func(in *pb.BasicMessage, seq uint64) *pb.BasicMessage
+
+// ParseReply returns the reply strategy from its specification, one of:
+//
+//	fixed        reply "reply msg #N" with the payload size the request asked for
+//	fixed:text   reply with the text and the payload size the request asked for
+//	echo         reply with the request message and payload verbatim
+//	reverse      reply with the request message and payload reversed
+//	upper        reply with the request message in upper case and its payload
+//	size:4K      reply "reply msg #N" with a payload of the configured size
+func ParseReply(spec string) (ReplyStrategy, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+	name := strings.ToLower(parts[0])
+
+	if len(parts) == 2 {
+		switch name {
+		case "fixed":
+			return FixedReply(parts[1]), nil
+		case "size":
+			size, err := ParseBytes(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			if size > MaxPayloadSize {
+				return nil, fmt.Errorf("reply size %d exceeds maximum of %d bytes", size, MaxPayloadSize)
+			}
+			return SizedReply(size), nil
+		}
+		return nil, fmt.Errorf("unknown reply strategy %q", spec)
+	}
+
+	switch name {
+	case "", "fixed":
+		return FixedReply(""), nil
+	case "echo":
+		return EchoReply, nil
+	case "reverse":
+		return ReverseReply, nil
+	case "upper":
+		return UpperReply, nil
+	}
+	return nil, fmt.Errorf("unknown reply strategy %q", spec)
+}
+
+// FixedReply replies with the text or with the message number if the text is
+// empty, along with a payload of the size requested by the client.
+func FixedReply(text string) ReplyStrategy {
+	return func(in *pb.BasicMessage, seq uint64) *pb.BasicMessage {
+		reply := &pb.BasicMessage{
+			Message: text,
+			Payload: Payload(int(in.GetReplySize())),
+		}
+
+		if text == "" {
+			reply.Message = fmt.Sprintf("reply msg #%d", seq)
+		}
+		return reply
+	}
+}
+
+// SizedReply replies with the message number and a payload of the specified
+// size, regardless of the payload size requested by the client.
+func SizedReply(size int) ReplyStrategy {
+	return func(in *pb.BasicMessage, seq uint64) *pb.BasicMessage {
+		return &pb.BasicMessage{
+			Message: fmt.Sprintf("reply msg #%d", seq),
+			Payload: Payload(size),
+		}
+	}
+}
+
+// EchoReply replies with the message and payload of the request verbatim.
+func EchoReply(in *pb.BasicMessage, seq uint64) *pb.BasicMessage {
+	return &pb.BasicMessage{
+		Message: in.GetMessage(),
+		Payload: in.GetPayload(),
+	}
+}
+
+// ReverseReply replies with the message and payload of the request reversed.
+func ReverseReply(in *pb.BasicMessage, seq uint64) *pb.BasicMessage {
+	runes := []rune(in.GetMessage())
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	payload := make([]byte, len(in.GetPayload()))
+	for i, b := range in.GetPayload() {
+		payload[len(payload)-1-i] = b
+	}
+
+	return &pb.BasicMessage{
+		Message: string(runes),
+		Payload: payload,
+	}
+}
+
+// UpperReply replies with the request message in upper case and its payload.
+func UpperReply(in *pb.BasicMessage, seq uint64) *pb.BasicMessage {
+	return &pb.BasicMessage{
+		Message: strings.ToUpper(in.GetMessage()),
+		Payload: in.GetPayload(),
+	}
+}
@@ -0,0 +1,137 @@
+package echo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// Defaults for the retry policy used by the command line clients.
+const (
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+	DefaultMultiplier     = 2.0
+	DefaultJitter         = 0.2
+)
+
+// DefaultRetryableCodes are the gRPC status codes that are generally safe to
+// retry because the server did not process the request.
+var DefaultRetryableCodes = []codes.Code{
+	codes.Unavailable, codes.ResourceExhausted, codes.Aborted,
+}
+
+// NewRetryPolicy creates a retry policy that will retry a message up to the
+// specified number of times with the default exponential backoff and codes.
+func NewRetryPolicy(retries int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    retries + 1,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+		Multiplier:     DefaultMultiplier,
+		Jitter:         DefaultJitter,
+		Retryable:      DefaultRetryableCodes,
+	}
+}
+
+// RetryPolicy describes how the client resends messages that fail. Backoff
+// between attempts grows exponentially from the initial backoff by the
+// multiplier up to the max backoff and is randomized by the jitter fraction.
+// A nil retry policy means that messages are never retried.
+type RetryPolicy struct {
+	MaxAttempts    int           // maximum number of attempts including the first
+	InitialBackoff time.Duration // backoff before the first retry
+	MaxBackoff     time.Duration // upper bound on the backoff between attempts
+	Multiplier     float64       // growth factor of the backoff per attempt
+	Jitter         float64       // fraction of the backoff to randomize, 0-1
+	Retryable      []codes.Code  // the status codes that can be retried
+}
+
+// Retry returns true if another attempt should be made after the specified
+// attempt (counting from 1) failed with the given error.
+func (p *RetryPolicy) Retry(attempt int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.IsRetryable(err)
+}
+
+// IsRetryable returns true if the gRPC status code of the error is one of the
+// retryable codes of the policy.
+func (p *RetryPolicy) IsRetryable(err error) bool {
+	if p == nil || err == nil {
+		return false
+	}
+
+	code := statusCode(err)
+	for _, retryable := range p.Retryable {
+		if code == retryable {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the amount of time to wait after the specified attempt
+// (counting from 1) before making the next attempt.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	if p == nil || attempt < 1 {
+		return 0
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	// Randomize the backoff by +/- the jitter fraction
+	if p.Jitter > 0 {
+		backoff *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// ParseCodes parses a comma separated list of gRPC status code names (case
+// insensitive and ignoring underscores, e.g. "unavailable,deadline_exceeded")
+// into status codes.
+func ParseCodes(s string) ([]codes.Code, error) {
+	names := make(map[string]codes.Code)
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		names[normalizeCode(code.String())] = code
+	}
+
+	var parsed []codes.Code
+	for _, name := range strings.Split(s, ",") {
+		name = normalizeCode(name)
+		if name == "" {
+			continue
+		}
+
+		code, ok := names[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown status code %q", name)
+		}
+		parsed = append(parsed, code)
+	}
+
+	return parsed, nil
+}
+
+// Helper to compare status code names without case, spaces or underscores.
+func normalizeCode(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.Replace(name, "_", "", -1)
+}
+
+// Helper to get the gRPC status code of an error, unwrapping library errors.
+func statusCode(err error) codes.Code {
+	if e, ok := err.(*Error); ok && e.err != nil {
+		return statusCode(e.err)
+	}
+	return gstatus.Code(err)
+}
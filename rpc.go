@@ -0,0 +1,207 @@
+package echo
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// rpcServiceName is the name the Hello service is registered with.
+const rpcServiceName = "Hello"
+
+// RPCTransport calls the Hello service with the standard library's net/rpc
+// package, which encodes messages with gob and multiplexes calls on a single
+// connection. Errors are sent as strings prefixed by the gRPC status code.
+type RPCTransport struct{}
+
+// String returns the name of the transport.
+func (RPCTransport) String() string {
+	return "rpc"
+}
+
+// Serve requests from each connection accepted on the listener in its own go
+// routine until the context is canceled.
+func (t RPCTransport) Serve(ctx context.Context, sock net.Listener, s *Server) error {
+	if err := checkInsecure(t, s.creds != nil); err != nil {
+		return err
+	}
+
+	if err := checkAuth(t, len(s.tokens) > 0); err != nil {
+		return err
+	}
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(rpcServiceName, &rpcHello{s}); err != nil {
+		return WrapError("could not register rpc service", err)
+	}
+
+	conns := newConnSet()
+
+	// Close the listener when the server is stopped so that accept returns
+	go func() {
+		<-ctx.Done()
+		sock.Close()
+	}()
+
+	for {
+		conn, err := sock.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				s.draining()
+				conns.drain(s.drain)
+				return nil
+			}
+			return err
+		}
+
+		conns.serve(conn, func() { srv.ServeConn(conn) })
+	}
+}
+
+// Dial connects to the server at the client's address.
+func (t RPCTransport) Dial(c *Client, timeout time.Duration) (Conn, error) {
+	if err := checkInsecure(t, c.creds != nil); err != nil {
+		return nil, err
+	}
+
+	if err := checkAuth(t, c.token != ""); err != nil {
+		return nil, err
+	}
+
+	conn := &rpcConn{addr: c.addr, timeout: timeout, dial: clientDialer(c)}
+	if _, err := conn.connect(); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// RPCArgs are the arguments of the Hello.Respond call.
+type RPCArgs struct {
+	Message *pb.BasicMessage
+	Timeout time.Duration
+}
+
+// rpcHello exposes the server's Respond method to net/rpc.
+type rpcHello struct {
+	s *Server
+}
+
+// Respond handles the request with the timeout sent by the client.
+func (h *rpcHello) Respond(args *RPCArgs, reply *pb.BasicMessage) error {
+	// gob does not send empty structs, so an empty message is received as nil
+	if args.Message == nil {
+		args.Message = new(pb.BasicMessage)
+	}
+
+	rep, err := respondWithin(h.s, args.Message, args.Timeout)
+	if err != nil {
+		st := gstatus.Convert(err)
+		return fmt.Errorf("%d:%s", st.Code(), st.Message())
+	}
+
+	*reply = *rep
+	return nil
+}
+
+// rpcConn makes concurrent calls on a single net/rpc connection, redialing
+// the server if the connection is shut down.
+type rpcConn struct {
+	sync.Mutex
+	addr    string
+	timeout time.Duration
+	dial    Dialer
+	client  *rpc.Client
+}
+
+func (c *rpcConn) Respond(ctx context.Context, in *pb.BasicMessage) (*pb.BasicMessage, error) {
+	client, err := c.connect()
+	if err != nil {
+		return nil, gstatus.Error(codes.Unavailable, err.Error())
+	}
+
+	args := &RPCArgs{Message: in}
+	if deadline, ok := ctx.Deadline(); ok {
+		args.Timeout = time.Until(deadline)
+	}
+
+	reply := new(pb.BasicMessage)
+	call := client.Go(rpcServiceName+".Respond", args, reply, make(chan *rpc.Call, 1))
+
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		return nil, gstatus.FromContextError(ctx.Err()).Err()
+	}
+
+	switch err := call.Error.(type) {
+	case nil:
+		return reply, nil
+	case rpc.ServerError:
+		return nil, parseRPCError(string(err))
+	default:
+		if err == rpc.ErrShutdown {
+			c.reset(client)
+		}
+		return nil, transportError(err)
+	}
+}
+
+func (c *rpcConn) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client == nil {
+		return nil
+	}
+
+	err := c.client.Close()
+	c.client = nil
+	return err
+}
+
+// connect returns the current rpc client, dialing the server if required.
+func (c *rpcConn) connect() (*rpc.Client, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client == nil {
+		conn, err := c.dial(c.addr, c.timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.client = rpc.NewClient(conn)
+	}
+	return c.client, nil
+}
+
+// reset closes the rpc client if it is still the current client so that the
+// server is redialed on the next call.
+func (c *rpcConn) reset(client *rpc.Client) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client == client {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// Helper to parse the "code:message" errors returned by the rpc server.
+func parseRPCError(err string) error {
+	parts := strings.SplitN(err, ":", 2)
+	if len(parts) == 2 {
+		if code, perr := strconv.ParseUint(parts[0], 10, 32); perr == nil {
+			return gstatus.Error(codes.Code(code), parts[1])
+		}
+	}
+	return gstatus.Error(codes.Unknown, err)
+}
@@ -0,0 +1,205 @@
+package echo
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is the width of the windows of a time series if none is
+// specified when streaming progress.
+const DefaultInterval = time.Second
+
+// NewSeries creates a time series that buckets completions into windows of
+// the interval beginning at the start time. If the start time is zero, the
+// series begins with the first completion recorded.
+func NewSeries(interval time.Duration, start time.Time) *Series {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Series{interval: interval, start: start}
+}
+
+// Series is a time series of the throughput and latency of completed
+// requests, bucketed into fixed-width windows so that warm-up, pauses and
+// degradation over the course of a run are visible in the results.
+type Series struct {
+	sync.RWMutex
+	interval time.Duration // the width of each window
+	start    time.Time     // the beginning of the first window
+	windows  []Window      // the completions in each window, grown on demand
+}
+
+// Window is the summary of requests completed during one interval.
+type Window struct {
+	Messages uint64        // the number of messages completed
+	Latency  time.Duration // the total latency of the completed messages
+	Max      time.Duration // the largest latency of a completed message
+}
+
+// Record the completion of messages at the specified time with the latency.
+// Completions before the start of the series are ignored.
+func (s *Series) Record(at time.Time, messages uint64, latency time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.start.IsZero() {
+		s.start = at
+	}
+
+	i := s.index(at)
+	if i < 0 {
+		return
+	}
+
+	s.grow(i + 1)
+	s.windows[i].Add(Window{Messages: messages, Latency: latency, Max: latency})
+}
+
+// Interval returns the width of each window of the series.
+func (s *Series) Interval() time.Duration {
+	return s.interval
+}
+
+// Snapshot returns the index and summary of the last window that was
+// complete at the specified time, or -1 if no window has completed yet.
+func (s *Series) Snapshot(at time.Time) (int, Window) {
+	s.RLock()
+	defer s.RUnlock()
+
+	i := s.index(at) - 1
+	if s.start.IsZero() || i < 0 {
+		return -1, Window{}
+	}
+
+	if i < len(s.windows) {
+		return i, s.windows[i]
+	}
+	return i, Window{}
+}
+
+// Merge the windows of another series with the same interval into this one,
+// aligning the windows of both series by their start times.
+func (s *Series) Merge(o *Series) {
+	s.Lock()
+	o.RLock()
+	defer s.Unlock()
+	defer o.RUnlock()
+
+	if o.start.IsZero() {
+		return
+	}
+
+	if s.start.IsZero() {
+		s.start = o.start
+	}
+
+	// If the other series started earlier, shift the windows of this series
+	if shift := s.index(o.start); shift < 0 {
+		windows := make([]Window, -shift, len(s.windows)-shift)
+		s.windows = append(windows, s.windows...)
+		s.start = s.start.Add(time.Duration(shift) * s.interval)
+	}
+
+	offset := s.index(o.start)
+	s.grow(offset + len(o.windows))
+	for i, window := range o.windows {
+		s.windows[offset+i].Add(window)
+	}
+}
+
+// Serialize the windows of the series for the results.
+func (s *Series) Serialize() []map[string]interface{} {
+	s.RLock()
+	defer s.RUnlock()
+
+	data := make([]map[string]interface{}, 0, len(s.windows))
+	for i, window := range s.windows {
+		item := window.Serialize(s.interval)
+		item["window"] = i
+		item["offset (sec)"] = (time.Duration(i) * s.interval).Seconds()
+		data = append(data, item)
+	}
+	return data
+}
+
+// index returns the window containing the time, which is negative if the
+// time is before the start of the series.
+func (s *Series) index(at time.Time) int {
+	offset := at.Sub(s.start)
+	if offset < 0 {
+		return int((offset - s.interval + 1) / s.interval)
+	}
+	return int(offset / s.interval)
+}
+
+// grow the series so that it has at least n windows.
+func (s *Series) grow(n int) {
+	if n > len(s.windows) {
+		s.windows = append(s.windows, make([]Window, n-len(s.windows))...)
+	}
+}
+
+// Add the completions of another window to this one.
+func (w *Window) Add(o Window) {
+	w.Messages += o.Messages
+	w.Latency += o.Latency
+	if o.Max > w.Max {
+		w.Max = o.Max
+	}
+}
+
+// Throughput returns the messages per second completed during the window.
+func (w Window) Throughput(interval time.Duration) float64 {
+	return float64(w.Messages) / interval.Seconds()
+}
+
+// MeanLatency returns the average latency per message completed in the window.
+func (w Window) MeanLatency() time.Duration {
+	if w.Messages == 0 {
+		return 0
+	}
+	return w.Latency / time.Duration(w.Messages)
+}
+
+// Serialize the window for the results.
+func (w Window) Serialize(interval time.Duration) map[string]interface{} {
+	return map[string]interface{}{
+		"messages":                   w.Messages,
+		"throughput (msg/sec)":       w.Throughput(interval),
+		"latency per message (nsec)": w.MeanLatency().Nanoseconds(),
+		"max latency (nsec)":         w.Max.Nanoseconds(),
+	}
+}
+
+// Summary returns a one line description of the window for progress reports.
+func (w Window) Summary(interval time.Duration) string {
+	return fmt.Sprintf(
+		"%d messages -- %0.3f msg/sec, %s latency per message, %s max latency",
+		w.Messages, w.Throughput(interval), w.MeanLatency(), w.Max,
+	)
+}
+
+// Helper to write a summary of each window to w as it completes until done is
+// closed. Windows that complete between reports are skipped.
+func reportProgress(w io.Writer, interval time.Duration, done <-chan struct{}, snapshot func(at time.Time) (int, Window)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := -1
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			i, window := snapshot(now)
+			if i <= last {
+				continue
+			}
+
+			last = i
+			fmt.Fprintf(w, "window %d at %s: %s\n", i, time.Duration(i+1)*interval, window.Summary(interval))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package echo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSeriesIndex(t *testing.T) {
+	start := time.Now()
+	s := NewSeries(time.Second, start)
+
+	// Times before the start round down to the preceding window
+	for offset, expected := range map[time.Duration]int{
+		-2*time.Second - 1:      -3,
+		-2 * time.Second:        -2,
+		-time.Second - 1:        -2,
+		-time.Second:            -1,
+		-1:                      -1,
+		0:                       0,
+		time.Second - 1:         0,
+		time.Second:             1,
+		2500 * time.Millisecond: 2,
+	} {
+		if idx := s.index(start.Add(offset)); idx != expected {
+			t.Errorf("expected offset %s to be in window %d, got %d", offset, expected, idx)
+		}
+	}
+}
+
+func TestMergeSeries(t *testing.T) {
+	start := time.Now()
+	messages := func(s *Series) []uint64 {
+		counts := make([]uint64, 0, len(s.windows))
+		for _, window := range s.windows {
+			counts = append(counts, window.Messages)
+		}
+		return counts
+	}
+
+	for name, tc := range map[string]struct {
+		offset   time.Duration // start of the other series relative to this one
+		start    time.Duration // expected start of the merged series
+		expected []uint64      // expected messages in each merged window
+	}{
+		"aligned":        {0, 0, []uint64{3, 0, 2}},
+		"later":          {time.Second, 0, []uint64{1, 2, 0, 2}},
+		"earlier":        {-2 * time.Second, -2 * time.Second, []uint64{2, 0, 3}},
+		"earlier offset": {-1500 * time.Millisecond, -2 * time.Second, []uint64{2, 0, 3}},
+	} {
+		s := NewSeries(time.Second, start)
+		s.Record(start.Add(500*time.Millisecond), 1, time.Millisecond)
+
+		other := NewSeries(time.Second, start.Add(tc.offset))
+		other.Record(start.Add(tc.offset), 2, time.Millisecond)
+		other.Record(start.Add(tc.offset+2*time.Second), 2, time.Millisecond)
+
+		s.Merge(other)
+		if !s.start.Equal(start.Add(tc.start)) {
+			t.Errorf("%s: expected merged series to start at %s, got %s", name, tc.start, s.start.Sub(start))
+		}
+
+		if counts := messages(s); !reflect.DeepEqual(counts, tc.expected) {
+			t.Errorf("%s: expected merged windows %v, got %v", name, tc.expected, counts)
+		}
+	}
+
+	// Merging into an empty series takes the start of the other series
+	s, other := NewSeries(time.Second, time.Time{}), NewSeries(time.Second, start)
+	other.Record(start, 1, time.Millisecond)
+	s.Merge(other)
+	if !s.start.Equal(start) || !reflect.DeepEqual(messages(s), []uint64{1}) {
+		t.Errorf("expected empty series to take the windows of the other series")
+	}
+}
@@ -1,35 +1,92 @@
 package echo
 
 import (
-	"fmt"
+	"io"
 	"net"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	pb "github.com/bbengfort/echo/msg"
+	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	gstatus "google.golang.org/grpc/status"
 )
 
-func NewServer(addr, name string) (*Server, error) {
+// DefaultDrainTimeout is how long the server waits for in-flight requests to
+// complete during a graceful stop before the connections are closed.
+const DefaultDrainTimeout = 5 * time.Second
+
+// HelloService is the name of the echo service reported by health checks.
+const HelloService = "msg.Hello"
+
+func NewServer(addr, name string, opts ...Option) (*Server, error) {
 	s := new(Server)
 	s.Init(addr, name)
+	for _, opt := range opts {
+		if err := opt.applyServer(s); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
 type Server struct {
-	name    string   // host information for the server
-	addr    string   // address to bind the server to
-	nSent   uint64   // number of messages sent
-	nRecv   uint64   // number of messages received
-	nBytes  uint64   // number of bytes sent
-	metrics *Metrics // keep track of server side statistics
+	name    string        // host information for the server
+	addr    string        // address to bind the server to
+	nSent   uint64        // number of messages sent, accessed atomically
+	nRecv   uint64        // number of messages received, accessed atomically
+	nBytes  uint64        // number of bytes sent, accessed atomically
+	metrics *Metrics      // keep track of server side statistics
+	replies ReplyStrategy // constructs the replies to requests
+
+	transport Transport          // the protocol requests are served with
+	mu        sync.Mutex         // protects the server during start and stop
+	cancel    context.CancelFunc // stops the transport, nil if not running
+	stopped   chan struct{}      // closed when the transport has stopped
+	drain     time.Duration      // time to wait for in-flight requests on stop
+	shutdown  sync.Once          // ensures the metrics are only flushed once
+	scrape    string             // address to expose prometheus metrics on, if any
+	interval  time.Duration      // the width of the metrics time series windows
+	progress  io.Writer          // writes interval snapshots while serving, if not nil
+	started   time.Time          // when the server began serving, protected by mu
+	admin     string             // address to serve the admin rpcs on, if any
+	outpath   string             // path to flush the metrics to when they are reset
+	health    *health.Server     // reports whether the server is serving requests
+	faults    Faults             // failures injected into replies, protected by mu
+	halt      <-chan struct{}    // closed when the server stops, ends injected hangs
+
+	unary     []grpc.UnaryServerInterceptor  // chain wrapping unary grpc requests
+	streams   []grpc.StreamServerInterceptor // chain wrapping streaming grpc requests
+	keepalive time.Duration                  // how often idle grpc connections are pinged
+	pingWait  time.Duration                  // how long to wait for a ping before closing
+	maxSize   int                            // largest grpc message received or sent, 0 is default
+
+	creds  credentials.TransportCredentials // tls credentials, nil if insecure
+	tokens []string                         // tokens allowed to make requests, nil allows all
+	nonces nonceCache                       // nonces of signed requests that were accepted
 }
 
 func (s *Server) Init(addr, name string) {
 	s.addr = addr
+	s.drain = DefaultDrainTimeout
+	s.replies = FixedReply("")
+	s.transport = GRPCTransport{}
 	s.metrics = new(Metrics)
 	s.metrics.Init()
 
+	// The server is not serving until it is run
+	s.health = health.NewServer()
+	for _, service := range []string{"", HelloService} {
+		s.health.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+
 	// if name is empty string, set it to the hostname
 	if name == "" {
 		name, _ = os.Hostname()
@@ -37,45 +94,466 @@ func (s *Server) Init(addr, name string) {
 	s.name = name
 }
 
-func (s *Server) Run() error {
+// SetReplyStrategy specifies how the server constructs its replies.
+func (s *Server) SetReplyStrategy(strategy ReplyStrategy) {
+	s.replies = strategy
+}
+
+// SetTransport specifies the protocol the server handles requests with.
+func (s *Server) SetTransport(transport Transport) {
+	s.transport = transport
+}
+
+// SetCredentials specifies the TLS credentials used to secure connections to
+// the server; if nil the server accepts insecure connections. Only the grpc
+// transport supports TLS.
+func (s *Server) SetCredentials(creds credentials.TransportCredentials) {
+	s.creds = creds
+}
+
+// SetUnaryInterceptors specifies the chain of interceptors that wrap unary
+// requests handled with gRPC, including the admin rpcs; the first interceptor
+// is the outermost. Only the grpc transport and admin server use interceptors.
+func (s *Server) SetUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) {
+	s.unary = interceptors
+}
+
+// SetStreamInterceptors specifies the chain of interceptors that wrap the
+// streaming rpcs handled with gRPC; the first interceptor is the outermost.
+func (s *Server) SetStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) {
+	s.streams = interceptors
+}
+
+// SetKeepalive specifies how often the server pings idle gRPC connections and
+// how long it waits for a ping to be acknowledged before closing the
+// connection; clients are permitted to ping the server as often as the
+// interval. If the interval is zero the gRPC defaults are used.
+func (s *Server) SetKeepalive(interval, timeout time.Duration) {
+	s.keepalive = interval
+	s.pingWait = timeout
+}
+
+// SetMaxMessageSize specifies the largest message in bytes the server receives
+// or sends with gRPC; if zero the gRPC defaults are used.
+func (s *Server) SetMaxMessageSize(size int) {
+	s.maxSize = size
+}
+
+// SetMetrics specifies the metrics the server records requests in, e.g. to
+// share the metrics with the application embedding the server. Must be called
+// before the server is run.
+func (s *Server) SetMetrics(metrics *Metrics) {
+	s.metrics = metrics
+}
+
+// SetTokens specifies the tokens that clients must present with each request,
+// either as a bearer token or by signing the request with the token; if empty
+// requests are not authenticated. Only the grpc transport supports tokens, and
+// health checks are not authenticated.
+func (s *Server) SetTokens(tokens ...string) {
+	s.tokens = tokens
+}
+
+// SetMetricsAddr specifies an address to serve the metrics on for Prometheus
+// to scrape while the server is running; if empty the metrics are not served.
+func (s *Server) SetMetricsAddr(addr string) {
+	s.scrape = addr
+}
+
+// SetAdminAddr specifies an address to serve the admin rpcs on with gRPC so
+// that servers using other transports can be inspected while running; if
+// empty the admin rpcs are only served by the gRPC transport.
+func (s *Server) SetAdminAddr(addr string) {
+	s.admin = addr
+}
+
+// SetOutpath specifies the path that metrics are appended to when they are
+// reset between runs; if empty the metrics are discarded on reset.
+func (s *Server) SetOutpath(path string) {
+	s.outpath = path
+}
+
+// SetFaults specifies the failures the server injects into its replies; the
+// faults can be changed while the server is running.
+func (s *Server) SetFaults(faults Faults) error {
+	if err := faults.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.faults = faults
+	s.mu.Unlock()
+
+	status("injecting %s", faults)
+	return nil
+}
+
+// Faults returns the failures currently injected into replies.
+func (s *Server) Faults() Faults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.faults
+}
+
+// SetInterval specifies the width of the windows of the throughput and handler
+// time series recorded in the metrics; if zero no time series is recorded.
+func (s *Server) SetInterval(interval time.Duration) {
+	s.interval = interval
+}
+
+// SetProgress specifies a writer to stream a summary of each window of the
+// time series to while the server is running; requires an interval.
+func (s *Server) SetProgress(w io.Writer) {
+	s.progress = w
+}
+
+// SetDrainTimeout specifies how long to wait for in-flight requests to
+// complete when the server is stopped before forcefully closing connections.
+func (s *Server) SetDrainTimeout(timeout time.Duration) {
+	s.drain = timeout
+}
+
+// Transport returns the protocol the server handles requests with.
+func (s *Server) Transport() Transport {
+	return s.transport
+}
+
+// Metrics returns the server side statistics of the requests handled.
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
+// Run the server on its address until the context is canceled or the server
+// is stopped, at which point the server is gracefully stopped.
+func (s *Server) Run(ctx context.Context) error {
 	sock, err := net.Listen("tcp", s.addr)
 	if err != nil {
 		return WrapError("could not listen on '%s'", err, s.addr)
 	}
 	defer sock.Close()
 
-	status("bound grpc server to %s with tcp socket", s.addr)
+	if s.scrape != "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		if err = serveMetrics(ctx, s.scrape, s.metrics); err != nil {
+			return err
+		}
+	}
+
+	if s.admin != "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		if err = serveAdmin(ctx, s.admin, s); err != nil {
+			return err
+		}
+	}
 
-	// Create the grpc server, handler, and listen
-	srv := grpc.NewServer()
-	pb.RegisterHelloServer(srv, s)
-	return srv.Serve(sock)
+	if s.creds != nil {
+		status("bound %s server to %s with tls socket", s.transport, s.addr)
+	} else {
+		status("bound %s server to %s with tcp socket", s.transport, s.addr)
+		if len(s.tokens) > 0 {
+			warn("authenticating requests without tls: bearer tokens are sent in cleartext")
+		}
+	}
+	return s.Serve(ctx, sock)
 }
 
-func (s *Server) Shutdown(path string) error {
-	status("%s", s.metrics)
-	if path != "" {
-		extra := map[string]interface{}{"server": "grpc"}
-		return s.metrics.Write(path, extra)
+// Serve requests on the listener with the transport until the context is
+// canceled or the server is stopped. Returns nil if the server was stopped
+// without error.
+func (s *Server) Serve(ctx context.Context, sock net.Listener) error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		return ErrServerRunning
 	}
-	return nil
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	s.stopped = stopped
+	s.halt = ctx.Done()
+	s.started = time.Now()
+	s.health.Resume()
+	s.mu.Unlock()
+
+	// Windows of the time series are aligned to the start of serving
+	if s.interval > 0 {
+		s.metrics.Track(s.interval, time.Now())
+
+		if s.progress != nil {
+			done := make(chan struct{})
+			reported := make(chan struct{})
+			defer func() {
+				close(done)
+				<-reported
+			}()
+
+			// The series is replaced when the metrics are reset
+			snapshot := func(at time.Time) (int, Window) {
+				return s.metrics.Series().Snapshot(at)
+			}
+
+			go func() {
+				defer close(reported)
+				reportProgress(s.progress, s.interval, done, snapshot)
+			}()
+		}
+	}
+
+	err := s.transport.Serve(ctx, sock, s)
+	s.health.Shutdown()
+
+	s.mu.Lock()
+	s.cancel()
+	s.cancel = nil
+	s.mu.Unlock()
+	close(stopped)
+	return err
+}
+
+// Stop the server gracefully, waiting up to the drain timeout for in-flight
+// requests to complete before forcefully closing all connections.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	if s.cancel == nil {
+		s.mu.Unlock()
+		return
+	}
+
+	s.cancel()
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	<-stopped
+}
+
+// draining is called by transports when they begin to stop, so that health
+// checks report that the server is no longer serving while requests drain.
+func (s *Server) draining() {
+	s.health.Shutdown()
+	status("stopping server, draining requests for up to %s", s.drain)
+}
+
+// extra returns the server information that is written with the metrics.
+func (s *Server) extra() map[string]interface{} {
+	return map[string]interface{}{"server": s.transport.String(), "tls": s.creds != nil}
+}
+
+// Shutdown the server and flush the metrics to the path. Metrics are only
+// written once, subsequent calls to Shutdown are ignored.
+func (s *Server) Shutdown(path string) (err error) {
+	s.shutdown.Do(func() {
+		s.Stop()
+		status("%s", s.metrics)
+		if path != "" {
+			err = s.metrics.Write(path, s.extra())
+		}
+	})
+	return err
 }
 
 // Respond implements the echo.HelloServer interface.
-func (s *Server) Respond(ctx context.Context, in *pb.BasicMessage) (*pb.BasicMessage, error) {
+func (s *Server) Respond(ctx context.Context, in *pb.BasicMessage) (reply *pb.BasicMessage, err error) {
+	start := time.Now()
+	s.metrics.Begin("respond")
+	defer func() { s.metrics.Finish(time.Since(start), err) }()
+
+	// Stop work on requests whose deadline has already passed
+	if err := ctx.Err(); err != nil {
+		s.metrics.Expire()
+		info("dropped expired request from %s: %s", in.Sender, err)
+		return nil, contextError(err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		trace("request from %s has %s remaining", in.Sender, time.Until(deadline))
+	}
+
 	// Log that we've received the message
-	s.nRecv++
+	seq := atomic.AddUint64(&s.nRecv, 1)
 	info("received: %s\n", in.String())
 	s.metrics.Increment(in.Sender)
+	s.metrics.Received(in.Sender, proto.Size(in))
+
+	if err = s.inject(ctx, in.Sender); err != nil {
+		return nil, err
+	}
 
 	// Construct the reply
-	reply := &pb.BasicMessage{
-		Sender:  s.name,
-		Message: fmt.Sprintf("reply msg #%d", s.nRecv),
+	reply, err = s.reply(in, seq)
+	if err != nil {
+		return nil, err
 	}
 
 	// Send the reply
-	s.nSent++
+	size := proto.Size(reply)
+	atomic.AddUint64(&s.nSent, 1)
+	atomic.AddUint64(&s.nBytes, uint64(size))
+	s.metrics.Sent(in.Sender, size)
 	s.metrics.Complete()
 	return reply, nil
 }
+
+// Stream implements the server streaming echo.HelloServer interface by
+// replying to the request with the number of messages the client requested.
+func (s *Server) Stream(in *pb.BasicMessage, stream pb.Hello_StreamServer) (err error) {
+	start := time.Now()
+	s.metrics.Begin("stream")
+	defer func() { s.metrics.Finish(time.Since(start), err) }()
+
+	seq := atomic.AddUint64(&s.nRecv, 1)
+	info("received: %s\n", in.String())
+	s.metrics.Increment(in.Sender)
+	s.metrics.Received(in.Sender, proto.Size(in))
+
+	if err = s.inject(stream.Context(), in.Sender); err != nil {
+		return err
+	}
+
+	repeat := in.Repeat
+	if repeat == 0 {
+		repeat = 1
+	}
+
+	for i := uint32(0); i < repeat; i++ {
+		// Stop streaming if the client is gone or the deadline has passed
+		if err := stream.Context().Err(); err != nil {
+			s.metrics.Expire()
+			return err
+		}
+
+		reply, err := s.reply(in, seq)
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+
+		size := proto.Size(reply)
+		atomic.AddUint64(&s.nSent, 1)
+		atomic.AddUint64(&s.nBytes, uint64(size))
+		s.metrics.Sent(in.Sender, size)
+	}
+
+	s.metrics.Complete()
+	return nil
+}
+
+// Collect implements the client streaming echo.HelloServer interface by
+// receiving all of the client's messages then replying once at the end.
+func (s *Server) Collect(stream pb.Hello_CollectServer) (err error) {
+	start := time.Now()
+	s.metrics.Begin("collect")
+	defer func() { s.metrics.Finish(time.Since(start), err) }()
+
+	var (
+		n    uint64
+		seq  uint64
+		last *pb.BasicMessage
+	)
+
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		n++
+		last = in
+		seq = atomic.AddUint64(&s.nRecv, 1)
+		trace("received: %s\n", in.String())
+		s.metrics.Increment(in.Sender)
+		s.metrics.Received(in.Sender, proto.Size(in))
+	}
+
+	// Reply to the last message received on the stream
+	info("received %d messages on client stream", n)
+	if err = s.inject(stream.Context(), last.GetSender()); err != nil {
+		return err
+	}
+
+	reply, err := s.reply(last, seq)
+	if err != nil {
+		return err
+	}
+
+	size := proto.Size(reply)
+	atomic.AddUint64(&s.nSent, 1)
+	atomic.AddUint64(&s.nBytes, uint64(size))
+	s.metrics.Sent(last.GetSender(), size)
+	s.metrics.Complete()
+	return stream.SendAndClose(reply)
+}
+
+// Echo implements the bidirectional streaming echo.HelloServer interface by
+// replying to every message received on the stream as it arrives.
+func (s *Server) Echo(stream pb.Hello_EchoServer) error {
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.echo(in, stream); err != nil {
+			return err
+		}
+	}
+}
+
+// echo replies to a single message on the bidirectional stream, the handler
+// time of each message is measured rather than the lifetime of the stream.
+func (s *Server) echo(in *pb.BasicMessage, stream pb.Hello_EchoServer) (err error) {
+	start := time.Now()
+	s.metrics.Begin("echo")
+	defer func() { s.metrics.Finish(time.Since(start), err) }()
+
+	seq := atomic.AddUint64(&s.nRecv, 1)
+	info("received: %s\n", in.String())
+	s.metrics.Increment(in.Sender)
+	s.metrics.Received(in.Sender, proto.Size(in))
+
+	if err = s.inject(stream.Context(), in.Sender); err != nil {
+		return err
+	}
+
+	reply, err := s.reply(in, seq)
+	if err != nil {
+		return err
+	}
+
+	if err = stream.Send(reply); err != nil {
+		return err
+	}
+
+	size := proto.Size(reply)
+	atomic.AddUint64(&s.nSent, 1)
+	atomic.AddUint64(&s.nBytes, uint64(size))
+	s.metrics.Sent(in.Sender, size)
+	s.metrics.Complete()
+	return nil
+}
+
+// reply constructs the reply to the message using the reply strategy of the
+// server; the message may be nil if a client stream sent no messages.
+func (s *Server) reply(in *pb.BasicMessage, seq uint64) (*pb.BasicMessage, error) {
+	if size := in.GetReplySize(); size > MaxPayloadSize {
+		return nil, gstatus.Errorf(codes.InvalidArgument, "reply size %d exceeds maximum of %d bytes", size, MaxPayloadSize)
+	}
+
+	reply := s.replies(in, seq)
+	reply.Sender = s.name
+	return reply, nil
+}
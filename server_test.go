@@ -0,0 +1,298 @@
+package echo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+func TestMain(m *testing.M) {
+	SetLogLevel(Silent)
+	os.Exit(m.Run())
+}
+
+// Number of concurrent go routines and messages per go routine in tests.
+const (
+	nWorkers  = 32
+	nMessages = 200
+)
+
+// runServer serves the server on a random local port, returning the address
+// to connect to and a function that stops the server.
+func runServer(t *testing.T, s *Server) (string, func()) {
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Serve(ctx, sock)
+	}()
+
+	stop := func() {
+		cancel()
+		if err := <-errc; err != nil {
+			t.Errorf("server stopped with error: %s", err)
+		}
+	}
+
+	return sock.Addr().String(), stop
+}
+
+func TestRespondConcurrent(t *testing.T) {
+	s, _ := NewServer("", "test")
+
+	var (
+		wg   sync.WaitGroup
+		seqs sync.Map
+		dups uint64
+	)
+
+	for i := 0; i < nWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sender := fmt.Sprintf("client-%d", i)
+
+			for j := 0; j < nMessages; j++ {
+				in := &pb.BasicMessage{Sender: sender, Message: "hello"}
+				reply, err := s.Respond(context.Background(), in)
+				if err != nil {
+					t.Errorf("could not respond: %s", err)
+					return
+				}
+
+				// Every reply must have a unique sequence number
+				if _, loaded := seqs.LoadOrStore(reply.Message, true); loaded {
+					atomic.AddUint64(&dups, 1)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	total := uint64(nWorkers * nMessages)
+	if n := atomic.LoadUint64(&s.nRecv); n != total {
+		t.Errorf("expected %d messages received, got %d", total, n)
+	}
+
+	if n := atomic.LoadUint64(&s.nSent); n != total {
+		t.Errorf("expected %d messages sent, got %d", total, n)
+	}
+
+	if dups > 0 {
+		t.Errorf("%d replies had duplicate sequence numbers", dups)
+	}
+
+	if n := s.metrics.Accesses(); n != total {
+		t.Errorf("expected %d accesses, got %d", total, n)
+	}
+
+	if n := s.metrics.NClients(); n != nWorkers {
+		t.Errorf("expected %d clients, got %d", nWorkers, n)
+	}
+
+	if n := s.metrics.InFlight(); n != 0 {
+		t.Errorf("expected no requests in flight, got %d", n)
+	}
+}
+
+func TestMetricsConcurrent(t *testing.T) {
+	s, _ := NewServer("", "test")
+	s.metrics.Track(time.Millisecond, time.Now())
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+
+	// Read the metrics while they are being written
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				s.metrics.Serialize(nil)
+				_ = s.metrics.String()
+				s.metrics.WritePrometheus(ioutil.Discard)
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for i := 0; i < nWorkers; i++ {
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			in := &pb.BasicMessage{Sender: fmt.Sprintf("client-%d", i)}
+			for j := 0; j < nMessages; j++ {
+				s.Respond(context.Background(), in)
+			}
+		}(i)
+	}
+
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	other := new(Metrics)
+	other.Init()
+	other.Append(s.metrics)
+
+	if n := other.Accesses(); n != nWorkers*nMessages {
+		t.Errorf("expected %d appended accesses, got %d", nWorkers*nMessages, n)
+	}
+
+	var buf strings.Builder
+	if err := other.WritePrometheus(&buf); err != nil {
+		t.Fatalf("could not write metrics: %s", err)
+	}
+
+	expected := fmt.Sprintf(`echo_requests_total{method="respond"} %d`, nWorkers*nMessages)
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("prometheus metrics did not contain %q", expected)
+	}
+}
+
+func TestMetricsDistributions(t *testing.T) {
+	m := new(Metrics)
+	m.Init()
+
+	// Handler durations are counted in the first bucket they do not exceed
+	for elapsed, bucket := range map[time.Duration]int{
+		50 * time.Microsecond:  0,
+		100 * time.Microsecond: 0,
+		101 * time.Microsecond: 1,
+		3 * time.Millisecond:   5,
+		10 * time.Second:       len(HandlerBuckets) - 1,
+		20 * time.Second:       len(HandlerBuckets),
+	} {
+		before := m.handler[bucket]
+		m.Begin("respond")
+		m.Finish(elapsed, nil)
+		if m.handler[bucket] != before+1 {
+			t.Errorf("expected handler time of %s to be counted in bucket %d", elapsed, bucket)
+		}
+	}
+
+	if n := m.handling.N(); n != 6 {
+		t.Errorf("expected 6 handler durations, got %d", n)
+	}
+
+	// Inter-arrival times are measured between messages from any client
+	m.Increment("a")
+	time.Sleep(2 * time.Millisecond)
+	m.Increment("b")
+	if n, total := m.arrivals.N(), time.Duration(m.arrivals.Total()); n != 1 || total < 2*time.Millisecond {
+		t.Errorf("expected one inter-arrival time of at least 2ms, got %d totaling %s", n, total)
+	}
+
+	m.Received("a", 10)
+	m.Received("a", 30)
+	m.Sent("a", 5)
+	m.Received("b", 7)
+
+	for _, tc := range []struct {
+		name   string
+		in     bool
+		client string
+		n      uint64
+		total  float64
+	}{
+		{"bytes in from a", true, "a", 2, 40},
+		{"bytes out to a", false, "a", 1, 5},
+		{"bytes in from b", true, "b", 1, 7},
+	} {
+		dist := m.bytesOut[tc.client]
+		if tc.in {
+			dist = m.bytesIn[tc.client]
+		}
+
+		if dist == nil || dist.N() != tc.n || dist.Total() != tc.total {
+			t.Errorf("expected %s to be %d messages totaling %v bytes", tc.name, tc.n, tc.total)
+		}
+	}
+
+	if _, ok := m.bytesOut["b"]; ok {
+		t.Error("expected no reply sizes to be recorded for b")
+	}
+
+	// Appending the metrics twice doubles every distribution
+	other := new(Metrics)
+	other.Init()
+	other.Append(m)
+	other.Append(m)
+
+	for i, count := range m.handler {
+		if other.handler[i] != 2*count {
+			t.Errorf("expected appended bucket %d to be %d, got %d", i, 2*count, other.handler[i])
+		}
+	}
+
+	if other.handling.N() != 12 || other.arrivals.N() != 2 || other.handled != 2*m.handled {
+		t.Errorf("expected appended handler and inter-arrival times to be combined")
+	}
+
+	if other.bytesIn["a"].Total() != 80 || other.bytesOut["a"].Total() != 10 || other.bytesIn["b"].N() != 2 {
+		t.Errorf("expected appended byte distributions to be combined per client")
+	}
+
+	// Reset returns the distributions and clears them
+	old := m.Reset()
+	if old.handling.N() != 6 || old.arrivals.N() != 1 || old.bytesIn["a"].N() != 2 {
+		t.Errorf("expected reset to return the distributions collected")
+	}
+
+	for i, count := range m.handler {
+		if count != 0 {
+			t.Errorf("expected bucket %d to be cleared, got %d", i, count)
+		}
+	}
+
+	if m.handling.N() != 0 || m.handled != 0 || len(m.bytesIn) != 0 || len(m.bytesOut) != 0 {
+		t.Errorf("expected reset to clear the distributions")
+	}
+
+	// The gap across a reset is not an inter-arrival time
+	m.Increment("a")
+	if n := m.arrivals.N(); n != 0 {
+		t.Errorf("expected no inter-arrival times after a reset, got %d", n)
+	}
+}
+
+func TestMetricsMessageSizes(t *testing.T) {
+	s, _ := NewServer("", "test")
+	s.SetReplyStrategy(EchoReply)
+
+	in := &pb.BasicMessage{Sender: "client-1", Message: "hello world"}
+	reply, err := s.Respond(context.Background(), in)
+	if err != nil {
+		t.Fatalf("could not respond: %s", err)
+	}
+
+	if total := s.metrics.bytesIn["client-1"].Total(); total != float64(proto.Size(in)) {
+		t.Errorf("expected %d bytes received, got %v", proto.Size(in), total)
+	}
+
+	if total := s.metrics.bytesOut["client-1"].Total(); total != float64(proto.Size(reply)) {
+		t.Errorf("expected %d bytes sent, got %v", proto.Size(reply), total)
+	}
+}
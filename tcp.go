@@ -0,0 +1,368 @@
+package echo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// maxFrameSize limits the length of a frame read by the tcp transport.
+const maxFrameSize = 2 * (MaxPayloadSize + 1024)
+
+// TCPTransport sends length-prefixed protocol buffers over raw TCP sockets.
+// Every frame is prefixed by its length as a 4 byte big endian integer. The
+// body of a request frame is the per-request timeout in nanoseconds as an 8
+// byte big endian integer (0 for no timeout) followed by the marshaled
+// message. The body of a reply frame is a 1 byte gRPC status code followed by
+// the marshaled reply if the code is OK or by the error message otherwise.
+// Each connection handles one request at a time.
+type TCPTransport struct{}
+
+// String returns the name of the transport.
+func (TCPTransport) String() string {
+	return "tcp"
+}
+
+// Serve requests from each connection accepted on the listener in its own go
+// routine until the context is canceled.
+func (t TCPTransport) Serve(ctx context.Context, sock net.Listener, s *Server) error {
+	if err := checkInsecure(t, s.creds != nil); err != nil {
+		return err
+	}
+
+	if err := checkAuth(t, len(s.tokens) > 0); err != nil {
+		return err
+	}
+
+	conns := newConnSet()
+
+	// Close the listener when the server is stopped so that accept returns
+	go func() {
+		<-ctx.Done()
+		sock.Close()
+	}()
+
+	for {
+		conn, err := sock.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				s.draining()
+				conns.drain(s.drain)
+				return nil
+			}
+			return err
+		}
+
+		conns.serve(conn, func() { serveTCP(conn, s) })
+	}
+}
+
+// Dial returns a connection that connects to the server when it is used.
+func (t TCPTransport) Dial(c *Client, timeout time.Duration) (Conn, error) {
+	if err := checkInsecure(t, c.creds != nil); err != nil {
+		return nil, err
+	}
+
+	if err := checkAuth(t, c.token != ""); err != nil {
+		return nil, err
+	}
+
+	conn := &tcpConn{addr: c.addr, timeout: timeout, dial: clientDialer(c)}
+	if err := conn.connect(); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// serveTCP handles requests from the connection until it is closed.
+func serveTCP(conn net.Conn, s *Server) {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		body, err := readFrame(r)
+		if err != nil {
+			if err != io.EOF {
+				debug("closing tcp connection from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		if len(body) < 8 {
+			debug("closing tcp connection from %s: short request frame", conn.RemoteAddr())
+			return
+		}
+
+		in := new(pb.BasicMessage)
+		if err := proto.Unmarshal(body[8:], in); err != nil {
+			debug("closing tcp connection from %s: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		reply, err := respondWithin(s, in, time.Duration(binary.BigEndian.Uint64(body[:8])))
+		if err = writeReply(w, reply, err); err == nil {
+			err = w.Flush()
+		}
+
+		if err != nil {
+			debug("closing tcp connection from %s: %s", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// respondWithin handles the request with the timeout sent by the client, if
+// any. Requests are not canceled when the server stops so that they can drain.
+func respondWithin(s *Server, in *pb.BasicMessage, timeout time.Duration) (*pb.BasicMessage, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return s.Respond(ctx, in)
+}
+
+// tcpConn sends requests on a single tcp connection, one at a time, and
+// reconnects on the next request if the connection fails.
+type tcpConn struct {
+	sync.Mutex
+	addr    string
+	timeout time.Duration
+	dial    Dialer
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+}
+
+func (c *tcpConn) Respond(ctx context.Context, in *pb.BasicMessage) (*pb.BasicMessage, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return nil, gstatus.Error(codes.Unavailable, err.Error())
+		}
+	}
+
+	// Bound the request by the deadline of the context
+	var timeout time.Duration
+	deadline, ok := ctx.Deadline()
+	if ok {
+		timeout = time.Until(deadline)
+	}
+	c.conn.SetDeadline(deadline)
+
+	reply, err := c.roundtrip(in, timeout)
+	if err != nil {
+		// The connection is out of sync after a failure, so reset it.
+		if _, ok := gstatus.FromError(err); !ok {
+			c.reset()
+			err = transportError(err)
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *tcpConn) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *tcpConn) connect() (err error) {
+	if c.conn, err = c.dial(c.addr, c.timeout); err != nil {
+		return err
+	}
+
+	c.r = bufio.NewReader(c.conn)
+	c.w = bufio.NewWriter(c.conn)
+	return nil
+}
+
+func (c *tcpConn) reset() {
+	c.conn.Close()
+	c.conn = nil
+}
+
+// roundtrip writes the request frame and reads the reply frame; errors
+// returned by the server are gRPC status errors, all others are i/o errors.
+func (c *tcpConn) roundtrip(in *pb.BasicMessage, timeout time.Duration) (*pb.BasicMessage, error) {
+	data, err := proto.Marshal(in)
+	if err != nil {
+		return nil, gstatus.Error(codes.Internal, err.Error())
+	}
+
+	body := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(body[:8], uint64(timeout))
+	copy(body[8:], data)
+
+	if err = writeFrame(c.w, body); err != nil {
+		return nil, err
+	}
+	if err = c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	if body, err = readFrame(c.r); err != nil {
+		return nil, err
+	}
+
+	if len(body) < 1 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if code := codes.Code(body[0]); code != codes.OK {
+		return nil, gstatus.Error(code, string(body[1:]))
+	}
+
+	reply := new(pb.BasicMessage)
+	if err = proto.Unmarshal(body[1:], reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+//===========================================================================
+// Framing helpers
+//===========================================================================
+
+// Helper to read a length-prefixed frame.
+func readFrame(r io.Reader) ([]byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > maxFrameSize {
+		return nil, WrapError("frame of %d bytes exceeds maximum size", nil, size)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Helper to write a length-prefixed frame.
+func writeFrame(w io.Writer, body []byte) error {
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(body)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(body)
+	return err
+}
+
+// Helper to write a reply frame with the status code of the error.
+func writeReply(w io.Writer, reply *pb.BasicMessage, err error) error {
+	if err != nil {
+		st := gstatus.Convert(err)
+		return writeFrame(w, append([]byte{byte(st.Code())}, st.Message()...))
+	}
+
+	data, err := proto.Marshal(reply)
+	if err != nil {
+		return writeReply(w, nil, gstatus.Error(codes.Internal, err.Error()))
+	}
+	return writeFrame(w, append([]byte{byte(codes.OK)}, data...))
+}
+
+// Helper to convert network errors into gRPC status errors so that they are
+// counted and retried in the same way as gRPC errors.
+func transportError(err error) error {
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return gstatus.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return gstatus.Error(codes.Unavailable, err.Error())
+}
+
+//===========================================================================
+// Connection tracking for graceful stops
+//===========================================================================
+
+// connSet tracks the connections accepted by a server so that they can be
+// drained when the server is stopped.
+type connSet struct {
+	sync.Mutex
+	wg    sync.WaitGroup
+	conns map[net.Conn]struct{}
+}
+
+func newConnSet() *connSet {
+	return &connSet{conns: make(map[net.Conn]struct{})}
+}
+
+// serve the connection with the handler in its own go routine, closing the
+// connection when the handler returns.
+func (s *connSet) serve(conn net.Conn, handler func()) {
+	s.Lock()
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	s.Unlock()
+
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.Lock()
+			delete(s.conns, conn)
+			s.Unlock()
+			conn.Close()
+		}()
+
+		handler()
+	}()
+}
+
+// drain interrupts reads on idle connections so that handlers return once any
+// in-flight request is complete, closing all connections after the timeout.
+func (s *connSet) drain(timeout time.Duration) {
+	s.Lock()
+	for conn := range s.conns {
+		if tc, ok := conn.(*net.TCPConn); ok {
+			tc.CloseRead()
+		} else {
+			conn.SetReadDeadline(time.Now())
+		}
+	}
+	s.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		warn("requests did not drain after %s, forcing stop", timeout)
+		s.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.Unlock()
+		<-done
+	}
+}
@@ -0,0 +1,223 @@
+package echo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// Names of the files written by GenerateCerts to the certificates directory.
+const (
+	CACertFile     = "ca.pem"
+	CAKeyFile      = "ca.key"
+	ServerCertFile = "server.pem"
+	ServerKeyFile  = "server.key"
+	ClientCertFile = "client.pem"
+	ClientKeyFile  = "client.key"
+)
+
+// CertValidity is how long certificates created by GenerateCerts are valid.
+const CertValidity = 365 * 24 * time.Hour
+
+//===========================================================================
+// Transport Credentials
+//===========================================================================
+
+// ServerCredentials loads the server certificate and key to serve TLS. If a
+// certificate authority is specified, the server also requires clients to
+// present a certificate signed by that authority (mutual TLS).
+func ServerCredentials(cert, key, ca string) (credentials.TransportCredentials, error) {
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, WrapError("could not load server certificate", err)
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if ca != "" {
+		if conf.ClientCAs, err = loadCertPool(ca); err != nil {
+			return nil, err
+		}
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(conf), nil
+}
+
+// ClientCredentials creates TLS credentials that verify the server with the
+// certificate authority, or with the system roots if ca is empty. If the
+// certificate and key are specified they are presented to the server for
+// mutual TLS. The server name overrides the host name used for verification.
+func ClientCredentials(cert, key, ca, serverName string) (credentials.TransportCredentials, error) {
+	conf := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if ca != "" {
+		pool, err := loadCertPool(ca)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+
+	if cert != "" || key != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, WrapError("could not load client certificate", err)
+		}
+		conf.Certificates = []tls.Certificate{pair}
+	}
+
+	return credentials.NewTLS(conf), nil
+}
+
+// Helper to load a PEM encoded certificate authority into a pool.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, WrapError("could not read certificate authority", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, WrapError("no certificates found in %s", nil, path)
+	}
+	return pool, nil
+}
+
+//===========================================================================
+// Certificate Generation
+//===========================================================================
+
+// GenerateCerts creates a self-signed certificate authority in the directory
+// along with a server certificate valid for the hosts (names or IP addresses)
+// and a client certificate, both signed by the authority, for local testing.
+func GenerateCerts(dir string, hosts []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	caTmpl := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"echo"}, CommonName: "echo local ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(CertValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := signCert(caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return err
+	}
+
+	if err := writePEM(dir, CACertFile, CAKeyFile, caDER, caKey); err != nil {
+		return err
+	}
+
+	// Create the server and client certificates signed by the authority
+	leaves := []struct {
+		name  string
+		usage x509.ExtKeyUsage
+		cert  string
+		key   string
+	}{
+		{"echo server", x509.ExtKeyUsageServerAuth, ServerCertFile, ServerKeyFile},
+		{"echo client", x509.ExtKeyUsageClientAuth, ClientCertFile, ClientKeyFile},
+	}
+
+	for _, leaf := range leaves {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+
+		tmpl := &x509.Certificate{
+			Subject:     pkix.Name{Organization: []string{"echo"}, CommonName: leaf.name},
+			NotBefore:   now.Add(-time.Hour),
+			NotAfter:    now.Add(CertValidity),
+			KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage: []x509.ExtKeyUsage{leaf.usage},
+		}
+
+		if leaf.usage == x509.ExtKeyUsageServerAuth {
+			for _, host := range hosts {
+				if ip := net.ParseIP(host); ip != nil {
+					tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+				} else {
+					tmpl.DNSNames = append(tmpl.DNSNames, host)
+				}
+			}
+		}
+
+		der, err := signCert(tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			return err
+		}
+
+		if err := writePEM(dir, leaf.cert, leaf.key, der, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Helper to sign a certificate template with a random serial number.
+func signCert(tmpl, parent *x509.Certificate, pub *ecdsa.PublicKey, priv *ecdsa.PrivateKey) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl.SerialNumber = serial
+	return x509.CreateCertificate(rand.Reader, tmpl, parent, pub, priv)
+}
+
+// Helper to write a certificate and its private key as PEM files in the dir.
+func writePEM(dir, certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(filepath.Join(dir, certFile), cert, 0644); err != nil {
+		return WrapError("could not write %s", err, certFile)
+	}
+
+	priv := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, keyFile), priv, 0600); err != nil {
+		return WrapError("could not write %s", err, keyFile)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package echo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// generate certificates for the loopback host into a temporary directory,
+// returning the directory and a function that removes it.
+func generate(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateCerts(dir, []string{"localhost", "127.0.0.1"}); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("could not generate certs: %s", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestGenerateCerts(t *testing.T) {
+	dir, cleanup := generate(t)
+	defer cleanup()
+
+	for _, name := range []string{CACertFile, CAKeyFile, ServerCertFile, ServerKeyFile, ClientCertFile, ClientKeyFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be generated: %s", name, err)
+		}
+	}
+
+	// Private keys are only readable by the owner
+	if info, err := os.Stat(filepath.Join(dir, ServerKeyFile)); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("expected server key to have mode 0600, got %s", info.Mode().Perm())
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	dir, cleanup := generate(t)
+	defer cleanup()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	for name, tc := range map[string]struct {
+		cert, key, ca string
+		valid         bool
+	}{
+		"tls":            {path(ServerCertFile), path(ServerKeyFile), "", true},
+		"mutual tls":     {path(ServerCertFile), path(ServerKeyFile), path(CACertFile), true},
+		"missing cert":   {path("missing.pem"), path(ServerKeyFile), "", false},
+		"mismatched key": {path(ServerCertFile), path(ClientKeyFile), "", false},
+		"missing ca":     {path(ServerCertFile), path(ServerKeyFile), path("missing.pem"), false},
+		"ca not a cert":  {path(ServerCertFile), path(ServerKeyFile), path(ServerKeyFile), false},
+	} {
+		if _, err := ServerCredentials(tc.cert, tc.key, tc.ca); (err == nil) != tc.valid {
+			t.Errorf("%s: expected server credentials valid=%t, got %v", name, tc.valid, err)
+		}
+
+		// The client loads the same files with the ca to verify the server
+		if _, err := ClientCredentials(tc.cert, tc.key, tc.ca, ""); (err == nil) != tc.valid {
+			t.Errorf("%s: expected client credentials valid=%t, got %v", name, tc.valid, err)
+		}
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, cleanup := generate(t)
+	defer cleanup()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	creds, err := ServerCredentials(path(ServerCertFile), path(ServerKeyFile), path(CACertFile))
+	if err != nil {
+		t.Fatalf("could not load server credentials: %s", err)
+	}
+
+	s, _ := NewServer("", "test", WithCredentials(creds))
+	addr, stop := runServer(t, s)
+	defer stop()
+
+	for name, tc := range map[string]struct {
+		cert, key string
+		valid     bool
+	}{
+		"client cert":    {path(ClientCertFile), path(ClientKeyFile), true},
+		"no client cert": {"", "", false},
+	} {
+		var creds credentials.TransportCredentials
+		if creds, err = ClientCredentials(tc.cert, tc.key, path(CACertFile), ""); err != nil {
+			t.Fatalf("%s: could not load client credentials: %s", name, err)
+		}
+
+		c, _ := NewClient(addr, "test", WithCredentials(creds))
+		if err := c.Connect(5 * time.Second); err != nil {
+			t.Fatalf("%s: could not connect: %s", name, err)
+		}
+
+		if err := c.Send("hello"); (err == nil) != tc.valid {
+			t.Errorf("%s: expected send valid=%t, got %v", name, tc.valid, err)
+		}
+		c.Close()
+	}
+
+	// A client that does not trust the authority cannot verify the server
+	c, _ := NewClient(addr, "test", WithCredentials(credentials.NewClientTLSFromCert(nil, "")))
+	if err := c.Connect(5 * time.Second); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer c.Close()
+
+	if err := c.Send("hello"); err == nil {
+		t.Error("expected server to be rejected by a client without the ca")
+	}
+}
@@ -0,0 +1,243 @@
+package echo
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// DefaultTransport is the name of the transport used if none is specified.
+const DefaultTransport = "grpc"
+
+// Transport is the protocol used to carry messages between the client and the
+// server, so that gRPC can be compared to other protocols in the same harness.
+// The server handles requests received by the transport with Respond; only
+// the gRPC transport supports the streaming RPCs and TLS.
+type Transport interface {
+	// String returns the name of the transport, which is recorded in results.
+	String() string
+
+	// Serve requests on the listener with the server until the context is
+	// canceled, then stop gracefully within the server's drain timeout.
+	// Returns nil if the server was stopped without error.
+	Serve(ctx context.Context, sock net.Listener, s *Server) error
+
+	// Dial connects the client to the server at the client's address.
+	Dial(c *Client, timeout time.Duration) (Conn, error)
+}
+
+// Conn is a client connection to the server made by a transport. Conns must
+// be safe to call Respond on from multiple go routines.
+type Conn interface {
+	// Respond sends the request and waits for the reply or for the context
+	// to be done. Errors should be gRPC status errors so they can be retried.
+	Respond(ctx context.Context, in *pb.BasicMessage) (*pb.BasicMessage, error)
+
+	// Close the connection to the server.
+	Close() error
+}
+
+// Dialer connects to the server at the address within the timeout. Clients
+// dial the server over the network unless a dialer is specified, e.g. to
+// connect to a server on an in-memory listener in tests.
+type Dialer func(addr string, timeout time.Duration) (net.Conn, error)
+
+// Helper to return the dialer of the client, dialing tcp if none is specified.
+func clientDialer(c *Client) Dialer {
+	if c.dialer != nil {
+		return c.dialer
+	}
+	return func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("tcp", addr, timeout)
+	}
+}
+
+// Transports returns the names of the available transports.
+func Transports() []string {
+	return []string{"grpc", "tcp", "http", "rpc"}
+}
+
+// ParseTransport returns the transport with the specified name.
+func ParseTransport(name string) (Transport, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "grpc":
+		return GRPCTransport{}, nil
+	case "tcp":
+		return TCPTransport{}, nil
+	case "http":
+		return HTTPTransport{}, nil
+	case "rpc":
+		return RPCTransport{}, nil
+	}
+	return nil, fmt.Errorf("unknown transport %q, use one of %s", name, strings.Join(Transports(), ", "))
+}
+
+// Helper to ensure that tls is only used with transports that support it.
+func checkInsecure(t Transport, secure bool) error {
+	if secure {
+		return WrapError("the %s transport does not support tls", nil, t)
+	}
+	return nil
+}
+
+// Helper to ensure that tokens are only used with transports that support them.
+func checkAuth(t Transport, auth bool) error {
+	if auth {
+		return WrapError("the %s transport does not support authentication", nil, t)
+	}
+	return nil
+}
+
+//===========================================================================
+// gRPC Transport
+//===========================================================================
+
+// GRPCTransport serves the Hello service with gRPC, including the streaming
+// RPCs, using the TLS credentials of the server and client if specified.
+type GRPCTransport struct{}
+
+// String returns the name of the transport.
+func (GRPCTransport) String() string {
+	return "grpc"
+}
+
+// Serve the Hello service on the listener until the context is canceled.
+func (GRPCTransport) Serve(ctx context.Context, sock net.Listener, s *Server) error {
+	srv := grpc.NewServer(serverOptions(s)...)
+	pb.RegisterHelloServer(srv, s)
+	registerAdmin(srv, s)
+
+	// Listen for requests in its own go routine
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(sock)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		s.draining()
+	}
+
+	// Stop the server gracefully, waiting up to the drain timeout for
+	// in-flight requests to complete before closing all connections.
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(s.drain):
+		warn("requests did not drain after %s, forcing stop", s.drain)
+		srv.Stop()
+	}
+
+	return <-errc
+}
+
+// Dial the server, securing the connection with the client's credentials.
+func (GRPCTransport) Dial(c *Client, timeout time.Duration) (Conn, error) {
+	security := grpc.WithInsecure()
+	if c.creds != nil {
+		security = grpc.WithTransportCredentials(c.creds)
+	}
+
+	opts := []grpc.DialOption{security, grpc.WithTimeout(timeout)}
+	if c.dialer != nil {
+		opts = append(opts, grpc.WithDialer(c.dialer))
+	}
+
+	unary, streams := c.unary, c.streams
+	if c.token != "" {
+		unary = append(unary[:len(unary):len(unary)], c.authUnary)
+		streams = append(streams[:len(streams):len(streams)], c.authStream)
+	}
+
+	if len(unary) > 0 {
+		opts = append(opts, grpc.WithUnaryInterceptor(ChainUnaryClient(unary...)))
+	}
+
+	if len(streams) > 0 {
+		opts = append(opts, grpc.WithStreamInterceptor(ChainStreamClient(streams...)))
+	}
+
+	if c.keepalive > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time: c.keepalive, Timeout: c.pingWait, PermitWithoutStream: true,
+		}))
+	}
+
+	if c.maxSize > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(c.maxSize), grpc.MaxCallSendMsgSize(c.maxSize),
+		))
+	}
+
+	cc, err := grpc.Dial(c.addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcConn{cc: cc, client: pb.NewHelloClient(cc)}, nil
+}
+
+// Helper to return the options of a gRPC server with the server's credentials,
+// interceptors, authentication, keepalive and message size limits.
+func serverOptions(s *Server) []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if s.creds != nil {
+		opts = append(opts, grpc.Creds(s.creds))
+	}
+
+	// Requests are authenticated within the user interceptors so that they
+	// can log and recover from rejected requests.
+	unary, streams := s.unary, s.streams
+	if len(s.tokens) > 0 {
+		unary = append(unary[:len(unary):len(unary)], s.authUnary)
+		streams = append(streams[:len(streams):len(streams)], s.authStream)
+	}
+
+	if len(unary) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(ChainUnaryServer(unary...)))
+	}
+
+	if len(streams) > 0 {
+		opts = append(opts, grpc.StreamInterceptor(ChainStreamServer(streams...)))
+	}
+
+	if s.keepalive > 0 {
+		opts = append(opts,
+			grpc.KeepaliveParams(keepalive.ServerParameters{Time: s.keepalive, Timeout: s.pingWait}),
+			grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: s.keepalive, PermitWithoutStream: true}),
+		)
+	}
+
+	if s.maxSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(s.maxSize), grpc.MaxSendMsgSize(s.maxSize))
+	}
+	return opts
+}
+
+// grpcConn wraps a gRPC client connection and exposes the Hello client so
+// that the streaming RPCs can also be used.
+type grpcConn struct {
+	cc     *grpc.ClientConn
+	client pb.HelloClient
+}
+
+func (c *grpcConn) Respond(ctx context.Context, in *pb.BasicMessage) (*pb.BasicMessage, error) {
+	return c.client.Respond(ctx, in)
+}
+
+func (c *grpcConn) Close() error {
+	return c.cc.Close()
+}
@@ -0,0 +1,225 @@
+package echo
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/bbengfort/echo/msg"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+var transports = []Transport{GRPCTransport{}, TCPTransport{}, HTTPTransport{}, RPCTransport{}}
+
+// eventually polls the condition until it is true or a second has passed.
+func eventually(cond func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return cond()
+}
+
+func TestTransportErrors(t *testing.T) {
+	for _, transport := range transports {
+		for _, tc := range []struct {
+			name   string
+			faults Faults
+			code   codes.Code
+		}{
+			{"injected error", Faults{ErrorRate: 1, ErrorCode: codes.ResourceExhausted}, codes.ResourceExhausted},
+			{"hang", Faults{HangRate: 1}, codes.DeadlineExceeded},
+		} {
+			s, c, stop := connect(t, transport)
+			s.SetFaults(tc.faults)
+
+			c.connMu.Lock()
+			c.timeout = 50 * time.Millisecond
+			c.connMu.Unlock()
+
+			err := c.Send("hello")
+			if code := statusCode(err); code != tc.code {
+				t.Errorf("%s %s: expected %s, got %v", transport, tc.name, tc.code, err)
+			}
+
+			// The server only ends a hang when the deadline it was sent passes
+			if tc.code == codes.DeadlineExceeded && !eventually(func() bool { return s.metrics.Errors() > 0 }) {
+				t.Errorf("%s: deadline was not sent to the server", transport)
+			}
+
+			// The connection is usable after the failure
+			s.SetFaults(Faults{})
+			if err := c.Send("hello"); err != nil {
+				t.Errorf("%s %s: expected send to succeed after the failure, got %v", transport, tc.name, err)
+			}
+			stop()
+		}
+	}
+}
+
+func TestTCPFramingError(t *testing.T) {
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	defer sock.Close()
+
+	// The first connection replies with an empty frame but stays open, the
+	// second connection echoes the request.
+	accepted := make(chan int, 2)
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := sock.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- i
+
+			go func(i int, conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					body, err := readFrame(r)
+					if err != nil {
+						return
+					}
+
+					if i == 0 {
+						writeFrame(conn, nil)
+						continue
+					}
+
+					in := new(pb.BasicMessage)
+					proto.Unmarshal(body[8:], in)
+					writeReply(conn, in, nil)
+				}
+			}(i, conn)
+		}
+	}()
+
+	c, _ := NewClient(sock.Addr().String(), "test")
+	conn := &tcpConn{addr: c.addr, timeout: time.Second, dial: clientDialer(c)}
+	if err := conn.connect(); err != nil {
+		t.Fatalf("could not connect: %s", err)
+	}
+	defer conn.Close()
+
+	in := &pb.BasicMessage{Sender: "test", Message: "hello"}
+	if _, err := conn.Respond(context.Background(), in); gstatus.Code(err) != codes.Unavailable {
+		t.Errorf("expected framing error to be unavailable, got %v", err)
+	}
+
+	if conn.conn != nil {
+		t.Error("expected connection to be reset after a framing error")
+	}
+
+	reply, err := conn.Respond(context.Background(), in)
+	if err != nil || reply.Message != "hello" {
+		t.Errorf("expected reply from a new connection, got %v (%v)", reply, err)
+	}
+
+	if n := len(accepted); n != 2 {
+		t.Errorf("expected the client to reconnect, got %d connections", n)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	s, _ := NewServer("", "test")
+	s.SetFaults(Faults{HangRate: 1})
+
+	for _, tc := range []struct {
+		name    string
+		method  string
+		body    string
+		timeout string
+		status  int
+		code    codes.Code
+	}{
+		{"get", http.MethodGet, "", "", http.StatusNotImplemented, codes.Unimplemented},
+		{"malformed body", http.MethodPost, "{", "", http.StatusBadRequest, codes.InvalidArgument},
+		{"malformed timeout", http.MethodPost, `{"sender":"test"}`, "soon", http.StatusBadRequest, codes.InvalidArgument},
+		{"timeout", http.MethodPost, `{"sender":"test"}`, "20000000", http.StatusGatewayTimeout, codes.DeadlineExceeded},
+	} {
+		req := httptest.NewRequest(tc.method, httpRespondPath, strings.NewReader(tc.body))
+		if tc.timeout != "" {
+			req.Header.Set(httpTimeoutHeader, tc.timeout)
+		}
+
+		w := httptest.NewRecorder()
+		serveHTTP(w, req, s)
+
+		herr := new(httpError)
+		if err := json.NewDecoder(w.Body).Decode(herr); err != nil {
+			t.Errorf("%s: could not decode error: %s", tc.name, err)
+			continue
+		}
+
+		if w.Code != tc.status || herr.Code != tc.code {
+			t.Errorf("%s: expected %d %s, got %d %s", tc.name, tc.status, tc.code, w.Code, herr.Code)
+		}
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for code, status := range map[codes.Code]int{
+		codes.OK:                 http.StatusOK,
+		codes.InvalidArgument:    http.StatusBadRequest,
+		codes.FailedPrecondition: http.StatusBadRequest,
+		codes.Unauthenticated:    http.StatusUnauthorized,
+		codes.PermissionDenied:   http.StatusForbidden,
+		codes.NotFound:           http.StatusNotFound,
+		codes.Aborted:            http.StatusConflict,
+		codes.ResourceExhausted:  http.StatusTooManyRequests,
+		codes.Canceled:           499,
+		codes.Unimplemented:      http.StatusNotImplemented,
+		codes.Unavailable:        http.StatusServiceUnavailable,
+		codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+		codes.Internal:           http.StatusInternalServerError,
+		codes.Unknown:            http.StatusInternalServerError,
+	} {
+		if actual := httpStatus(code); actual != status {
+			t.Errorf("expected %s to map to http status %d, got %d", code, status, actual)
+		}
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	for err, expected := range map[string]struct {
+		code codes.Code
+		msg  string
+	}{
+		"4:deadline exceeded":    {codes.DeadlineExceeded, "deadline exceeded"},
+		"14:message: with colon": {codes.Unavailable, "message: with colon"},
+		"8:":                     {codes.ResourceExhausted, ""},
+		"not a code":             {codes.Unknown, "not a code"},
+		"x:not a number":         {codes.Unknown, "x:not a number"},
+	} {
+		st := gstatus.Convert(parseRPCError(err))
+		if st.Code() != expected.code || st.Message() != expected.msg {
+			t.Errorf("expected %q to parse as %s %q, got %s %q", err, expected.code, expected.msg, st.Code(), st.Message())
+		}
+	}
+
+	// The server sends the timeout and status code of the error
+	s, _ := NewServer("", "test")
+	s.SetFaults(Faults{HangRate: 1})
+	h := &rpcHello{s}
+
+	err := h.Respond(&RPCArgs{Message: &pb.BasicMessage{Sender: "test"}, Timeout: 20 * time.Millisecond}, new(pb.BasicMessage))
+	if err == nil {
+		t.Fatal("expected hung request to fail")
+	}
+
+	if code := gstatus.Code(parseRPCError(err.Error())); code != codes.DeadlineExceeded {
+		t.Errorf("expected hung request to exceed its deadline, got %v", err)
+	}
+}